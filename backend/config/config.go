@@ -38,18 +38,172 @@ func InitDB() (*gorm.DB, error) {
 		return nil, err
 	}
 
+	// Companies registered before email verification count as verified.
+	verifiedBefore := db.Migrator().HasColumn(&models.Companies{}, "EmailVerifiedAt")
+
 	if err := db.AutoMigrate(
 		&models.Companies{},
+		&models.User{},
+		&models.UserInvitation{},
+		&models.APIKey{},
+		&models.Session{},
+		&models.EmailToken{},
+		&models.SigningKey{},
 		&models.Warehouse{},
 		&models.Products{},
 		&models.InventoryStock{},
+		&models.StockMovement{},
+		&models.StockReservation{},
+		&models.StockTransfer{},
+		&models.StockTransferLine{},
 		&models.PermissionRequest{},
+		&models.PermissionScope{},
+		&models.PermissionRequestHistory{},
+		&models.PermissionMessage{},
+		&models.Checkout{},
 		&models.Order{},
 		&models.OrderItem{},
+		&models.OrderTaxLine{},
+		&models.ExchangeRate{},
+		&models.OrderStatusHistory{},
+		&models.Shipment{},
+		&models.ShipmentLine{},
+		&models.ReturnRequest{},
+		&models.ReturnLine{},
+		&models.InvoiceSequence{},
+		&models.Invoice{},
+		&models.PriceBreak{},
+		&models.Coupon{},
+		&models.BuyerDiscount{},
+		&models.OrderItemDiscount{},
+		&models.PriceList{},
+		&models.PriceListItem{},
 	); err != nil {
 		return nil, err
 	}
 
+	if err := backfillStockLedger(db); err != nil {
+		return nil, err
+	}
+	if err := backfillOrderSellers(db); err != nil {
+		return nil, err
+	}
+	if err := backfillShippedQuantities(db); err != nil {
+		return nil, err
+	}
+	if err := backfillOrderSubtotals(db); err != nil {
+		return nil, err
+	}
+	if err := backfillOrderCurrencyTotals(db); err != nil {
+		return nil, err
+	}
+	if err := backfillOrderLineTotals(db); err != nil {
+		return nil, err
+	}
+	if err := backfillCompanyOwners(db); err != nil {
+		return nil, err
+	}
+	if !verifiedBefore {
+		if err := db.Exec(`UPDATE companies SET email_verified_at = created_at WHERE email_verified_at IS NULL`).Error; err != nil {
+			return nil, err
+		}
+	}
+
 	fmt.Println("Database initialized successfully")
 	return db, nil
 }
+
+// backfillStockLedger records an opening balance for every stock row that predates
+// the ledger, so that summing stock_movements always reproduces quantity_in_stock.
+func backfillStockLedger(db *gorm.DB) error {
+	return db.Exec(`
+        INSERT INTO stock_movements
+            (created_at, product_id, warehouse_id, company_id, movement_type, reason_code, quantity, balance_after, occurred_at)
+        SELECT NOW(), s.product_id, s.warehouse_id, p.supplier_id, ?, 'opening_balance',
+               s.quantity_in_stock, s.quantity_in_stock, s.created_at
+        FROM inventory_stocks s
+        JOIN products p ON p.id = s.product_id
+        WHERE s.deleted_at IS NULL AND s.quantity_in_stock > 0
+          AND NOT EXISTS (
+            SELECT 1 FROM stock_movements m
+            WHERE m.product_id = s.product_id AND m.warehouse_id = s.warehouse_id
+          )`, models.MovementAdjustment).Error
+}
+
+// backfillOrderSellers sets the seller of orders that predate per-supplier
+// checkout splitting when all of their items come from a single supplier.
+func backfillOrderSellers(db *gorm.DB) error {
+	return db.Exec(`
+        UPDATE orders o SET seller_id = s.supplier_id
+        FROM (
+            SELECT oi.order_id, MIN(p.supplier_id) AS supplier_id
+            FROM order_items oi
+            JOIN products p ON p.id = oi.product_id
+            GROUP BY oi.order_id
+            HAVING COUNT(DISTINCT p.supplier_id) = 1
+        ) s
+        WHERE o.id = s.order_id AND (o.seller_id IS NULL OR o.seller_id = 0)`).Error
+}
+
+// backfillShippedQuantities marks the items of orders accepted before shipments
+// were tracked as fully shipped. Such orders hold no active reservations and
+// have no shipments; their stock was already taken out when they were accepted.
+func backfillShippedQuantities(db *gorm.DB) error {
+	return db.Exec(`
+        UPDATE order_items SET quantity_shipped = quantity
+        WHERE quantity_shipped = 0 AND order_id IN (
+            SELECT o.id FROM orders o
+            WHERE o.status IN ?
+              AND NOT EXISTS (SELECT 1 FROM shipments s WHERE s.order_id = o.id)
+              AND NOT EXISTS (
+                SELECT 1 FROM stock_reservations r
+                WHERE r.order_id = o.id AND r.status = ? AND r.deleted_at IS NULL
+              )
+        )`,
+		[]string{models.OrderProcessing, models.OrderDelivered, models.OrderCompleted},
+		models.ReservationActive).Error
+}
+
+// backfillOrderSubtotals sets the subtotal of orders placed before consumption
+// tax was charged. Their total was the bare sum of their items, with no tax.
+func backfillOrderSubtotals(db *gorm.DB) error {
+	return db.Exec(`
+        UPDATE orders SET subtotal = total
+        WHERE subtotal = 0 AND tax_total = 0 AND total <> 0
+          AND NOT EXISTS (SELECT 1 FROM order_tax_lines t WHERE t.order_id = orders.id)`).Error
+}
+
+// backfillOrderCurrencyTotals fills the converted totals and prices of orders
+// placed before multi-currency support. Those were all in the default currency.
+func backfillOrderCurrencyTotals(db *gorm.DB) error {
+	if err := db.Exec(`
+        UPDATE orders SET buyer_total = total, seller_total = total
+        WHERE buyer_total = 0 AND seller_total = 0 AND total <> 0`).Error; err != nil {
+		return err
+	}
+	return db.Exec(`UPDATE order_items SET buyer_price = price WHERE buyer_price = 0 AND price <> 0`).Error
+}
+
+// backfillOrderLineTotals fills the line totals of items ordered before
+// discounts existed, when every line was charged its unit price.
+func backfillOrderLineTotals(db *gorm.DB) error {
+	if err := db.Exec(`
+        UPDATE order_items SET line_total = price * quantity, list_price = price
+        WHERE line_total = 0 AND price <> 0`).Error; err != nil {
+		return err
+	}
+	return db.Exec(`UPDATE return_lines SET amount = price * quantity WHERE amount = 0 AND price <> 0`).Error
+}
+
+// backfillCompanyOwners creates the owner user of companies registered before
+// users existed, with the company's email and password, so that their logins keep working.
+func backfillCompanyOwners(db *gorm.DB) error {
+	return db.Exec(`
+        INSERT INTO users (created_at, updated_at, company_id, email, name, password_hash, role, status)
+        SELECT NOW(), NOW(), c.id, c.email, c.name, c.password_hash, ?, ?
+        FROM companies c
+        WHERE c.deleted_at IS NULL
+          AND NOT EXISTS (SELECT 1 FROM users u WHERE u.company_id = c.id)
+          AND NOT EXISTS (SELECT 1 FROM users u WHERE u.email = c.email)`,
+		models.RoleOwner, models.UserActive).Error
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"backend/middleware"
+	"backend/models"
+	"backend/utils"
+)
+
+// apiKeyDisplayLength is the number of leading characters of a key kept in APIKey.Prefix.
+const apiKeyDisplayLength = 12
+
+// GetAPIKeysHandler lists the API keys of the authenticated company, newest first.
+// Revoked keys are included so that their last use stays visible.
+func GetAPIKeysHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		keys := []models.APIKey{}
+		if err := db.Where("company_id = ?", companyID).Order("created_at DESC").Find(&keys).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API keys"})
+			return
+		}
+		c.JSON(http.StatusOK, keys)
+	}
+}
+
+// CreateAPIKeyHandler creates an API key for the company. The key itself is
+// only returned here. scopes lists, comma separated, the permission areas the
+// key may use (catalog, inventory, purchasing, sales, fulfilment, finance);
+// empty allows all of them. A key is rejected outside its scopes, for reads
+// as well as changes, and never reaches the company settings, users or keys.
+// Expected JSON body: { "name": "ERP sync", "scopes": "inventory,fulfilment", "expires_at": "2027-01-01T00:00:00Z" }
+func CreateAPIKeyHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		var reqBody struct {
+			Name      string     `json:"name"`
+			Scopes    string     `json:"scopes"`
+			ExpiresAt *time.Time `json:"expires_at"`
+		}
+		if err := c.BindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		reqBody.Name = strings.TrimSpace(reqBody.Name)
+		if reqBody.Name == "" || len(reqBody.Name) > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Name must be between 1 and 100 characters"})
+			return
+		}
+		if reqBody.ExpiresAt != nil && !reqBody.ExpiresAt.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+			return
+		}
+		scopes := []string{}
+		seen := map[string]bool{}
+		for _, scope := range strings.Split(reqBody.Scopes, ",") {
+			scope = strings.TrimSpace(scope)
+			if scope == "" || seen[scope] {
+				continue
+			}
+			if !middleware.ValidArea(scope) || scope == middleware.AreaCompany {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scope " + scope})
+				return
+			}
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+
+		key, hash, err := utils.NewToken(models.APIKeyPrefix)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+			return
+		}
+		apiKey := models.APIKey{
+			CompanyID:       companyID,
+			Name:            reqBody.Name,
+			Prefix:          key[:apiKeyDisplayLength],
+			KeyHash:         hash,
+			Scopes:          strings.Join(scopes, ","),
+			CreatedByUserID: c.GetUint("userID"),
+			ExpiresAt:       reqBody.ExpiresAt,
+		}
+		if err := db.Create(&apiKey).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"api_key": apiKey, "key": key})
+	}
+}
+
+// RevokeAPIKeyHandler revokes an API key; requests using it are rejected from then on.
+func RevokeAPIKeyHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		keyID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key id"})
+			return
+		}
+		res := db.Model(&models.APIKey{}).
+			Where("id = ? AND company_id = ? AND revoked_at IS NULL", keyID, companyID).
+			Update("revoked_at", time.Now())
+		if res.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+			return
+		}
+		if res.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+	}
+}
@@ -2,8 +2,8 @@ package handlers
 
 import (
 	"errors"
+	"log"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
@@ -13,26 +13,24 @@ import (
 	"gorm.io/gorm"
 
 	"backend/models"
+	"backend/signing"
 )
 
-// generateToken creates a JWT for the given email.
-// In production, load your secret from environment variables.
-func generateToken(user models.Companies) (string, error) {
-	secret := []byte(os.Getenv("JWT_SECRET"))
-	if len(secret) == 0 {
-		return "", errors.New("JWT_SECRET environment variable is not set")
-	}
-
+// generateToken creates a JWT for the given user and session, carrying its company and role.
+func generateToken(user models.User, sessionID uint) (string, error) {
 	claims := jwt.MapClaims{
-		"companyID": user.ID,
+		"companyID": user.CompanyID,
+		"userID":    user.ID,
+		"role":      user.Role,
 		"email":     user.Email,
-		"exp":       time.Now().Add(8 * time.Hour).Unix(),
+		"sid":       sessionID,
+		"exp":       time.Now().Add(AccessTokenTTL).Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secret)
+	return signing.Sign(claims)
 }
 
 // LoginHandler handles user login. Expects { "email": ..., "password": ... }.
+// Users log in with their own email; a company's first user has the company's email.
 func LoginHandler(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req struct {
@@ -51,7 +49,7 @@ func LoginHandler(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		var user models.Companies
+		var user models.User
 		if err := db.Where("email = ?", req.Email).First(&user).Error; errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 			return
@@ -61,21 +59,34 @@ func LoginHandler(db *gorm.DB) gin.HandlerFunc {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 			return
 		}
+		if user.Status != models.UserActive {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This user has been disabled"})
+			return
+		}
+		// The company may have deleted its account.
+		var company models.Companies
+		if err := db.First(&company, user.CompanyID).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+			return
+		}
+		if company.Status != "active" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This company has been deactivated"})
+			return
+		}
 
-		token, err := generateToken(user)
+		response, err := startSession(c, db, &user)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{
-			"email": user.Email,
-			"token": token,
-		})
+		c.JSON(http.StatusOK, response)
 	}
 }
 
 // RegisterHandler handles user registration. Expects { "email": ..., "password": ... }.
+// It creates the company and its owner user, who logs in with the company's email,
+// and emails a link confirming the address.
 func RegisterHandler(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req struct {
@@ -85,6 +96,8 @@ func RegisterHandler(db *gorm.DB) gin.HandlerFunc {
 			Email    string `json:"email" binding:"required,email"`
 			Password string `json:"password" binding:"required,min=8"`
 			Status   string `json:"status" binding:"required"`
+			// Optional qualified invoice issuer number.
+			RegistrationNumber string `json:"registration_number"`
 		}
 		if err := c.BindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
@@ -97,13 +110,25 @@ func RegisterHandler(db *gorm.DB) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Company name, email, and password cannot be empty"})
 			return
 		}
+		req.RegistrationNumber = strings.ToUpper(strings.TrimSpace(req.RegistrationNumber))
+		if req.RegistrationNumber != "" && !registrationNumberPattern.MatchString(req.RegistrationNumber) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Registration number must be T followed by 13 digits"})
+			return
+		}
+
+		// The email must not belong to a user of another company.
+		var userCount int64
+		if err := db.Model(&models.User{}).Where("email = ?", req.Email).Count(&userCount).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing users"})
+			return
+		}
 
 		// Check for an existing user (including soft-deleted ones).
 		var existing models.Companies
 		err := db.Unscoped().Where("email = ?", req.Email).First(&existing).Error
 		if err == nil {
 			// If record exists and is active (not soft-deleted), return conflict.
-			if existing.DeletedAt.Time.IsZero() {
+			if existing.DeletedAt.Time.IsZero() || userCount > 0 {
 				c.JSON(http.StatusConflict, gin.H{"error": "User already exists"})
 				return
 			}
@@ -114,13 +139,29 @@ func RegisterHandler(db *gorm.DB) gin.HandlerFunc {
 				return
 			}
 			existing.PasswordHash = string(hashedPassword)
+			// The new registrant has to prove the email again.
+			existing.EmailVerifiedAt = nil
 			existing.DeletedAt.Time = time.Time{}
 			existing.DeletedAt.Valid = false
-			if err := db.Unscoped().Save(&existing).Error; err != nil {
+			err = db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Unscoped().Save(&existing).Error; err != nil {
+					return err
+				}
+				return restoreOwner(tx, &existing)
+			})
+			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to re-register user"})
 				return
 			}
-			c.JSON(http.StatusCreated, gin.H{"message": "User re-registered successfully"})
+			c.JSON(http.StatusCreated, gin.H{
+				"message":                 "User re-registered successfully",
+				"verification_email_sent": verifyRegisteredEmail(db, &existing),
+			})
+			return
+		}
+
+		if userCount > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "User already exists"})
 			return
 		}
 
@@ -132,28 +173,37 @@ func RegisterHandler(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		user := models.Companies{
-			Name:         req.Name,
-			Address:      req.Address,
-			Phone:        req.Phone,
-			Email:        req.Email,
-			PasswordHash: string(hashedPassword),
-			Status:       req.Status,
-		}
-		if err := db.Create(&user).Error; err != nil {
+			Name:               req.Name,
+			Address:            req.Address,
+			Phone:              req.Phone,
+			Email:              req.Email,
+			PasswordHash:       string(hashedPassword),
+			Status:             req.Status,
+			RegistrationNumber: req.RegistrationNumber,
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+			return restoreOwner(tx, &user)
+		})
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 			return
 		}
 
-		c.JSON(http.StatusCreated, gin.H{"message": "User registered successfully"})
+		c.JSON(http.StatusCreated, gin.H{
+			"message":                 "User registered successfully",
+			"verification_email_sent": verifyRegisteredEmail(db, &user),
+		})
 	}
 }
 
 // ChangePasswordHandler allows an authenticated user to change their password.
 func ChangePasswordHandler(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		email := c.GetString("email")
-		if email == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		user, ok := currentUser(c, db)
+		if !ok {
 			return
 		}
 
@@ -166,12 +216,6 @@ func ChangePasswordHandler(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		var user models.Companies
-		if err := db.Where("email = ?", email).First(&user).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-			return
-		}
-
 		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.OldPassword)); err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Old password is incorrect"})
 			return
@@ -183,8 +227,14 @@ func ChangePasswordHandler(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		user.PasswordHash = string(hashedPassword)
-		if err := db.Save(&user).Error; err != nil {
+		// Other sessions end; the one changing the password stays logged in.
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(user).Update("password_hash", string(hashedPassword)).Error; err != nil {
+				return err
+			}
+			return revokeSessions(tx, models.SessionPasswordChanged, "user_id = ? AND id <> ?", user.ID, c.GetUint("sessionID"))
+		})
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
 			return
 		}
@@ -193,16 +243,38 @@ func ChangePasswordHandler(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
-// DeleteAccountHandler deletes the authenticated user's account.
+// DeleteAccountHandler deletes the authenticated company's account together
+// with its users. Only an owner may do this.
 func DeleteAccountHandler(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		email := c.GetString("email")
-		if email == "" {
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 			return
 		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+		if c.GetString("role") != models.RoleOwner {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only an owner can delete the account"})
+			return
+		}
 
-		if err := db.Where("email = ?", email).Delete(&models.Companies{}).Error; err != nil {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("company_id = ?", companyID).Delete(&models.UserInvitation{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("company_id = ?", companyID).Delete(&models.User{}).Error; err != nil {
+				return err
+			}
+			if err := revokeSessions(tx, models.SessionAccountDeleted, "company_id = ?", companyID); err != nil {
+				return err
+			}
+			return tx.Delete(&models.Companies{}, companyID).Error
+		})
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
 			return
 		}
@@ -210,3 +282,46 @@ func DeleteAccountHandler(db *gorm.DB) gin.HandlerFunc {
 		c.JSON(http.StatusOK, gin.H{"message": "Account deleted successfully"})
 	}
 }
+
+// verifyRegisteredEmail sends a newly registered company the link confirming
+// its email, on behalf of its owner. It reports whether the email was sent;
+// the owner can ask for it again after logging in.
+func verifyRegisteredEmail(db *gorm.DB, company *models.Companies) bool {
+	var owner models.User
+	if err := db.Where("email = ?", company.Email).First(&owner).Error; err != nil {
+		return false
+	}
+	if err := startEmailVerification(db, company, &owner); err != nil {
+		log.Println("Error sending verification email:", err)
+		return false
+	}
+	return true
+}
+
+// restoreOwner gives a newly registered or re-registered company its owner
+// user, with the company's email and password. A user left behind by an
+// earlier deletion of the company is restored.
+func restoreOwner(tx *gorm.DB, company *models.Companies) error {
+	var owner models.User
+	err := tx.Unscoped().Where("email = ?", company.Email).First(&owner).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return tx.Create(&models.User{
+			CompanyID:    company.ID,
+			Email:        company.Email,
+			Name:         company.Name,
+			PasswordHash: company.PasswordHash,
+			Role:         models.RoleOwner,
+			Status:       models.UserActive,
+		}).Error
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Unscoped().Model(&owner).Updates(map[string]interface{}{
+		"company_id":    company.ID,
+		"password_hash": company.PasswordHash,
+		"role":          models.RoleOwner,
+		"status":        models.UserActive,
+		"deleted_at":    nil,
+	}).Error
+}
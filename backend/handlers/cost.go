@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -9,6 +10,137 @@ import (
 	"backend/models"
 )
 
+// taxRateTotal is the taxable amount and tax of one rate summed over several orders.
+type taxRateTotal struct {
+	TaxRate       uint         `json:"tax_rate"`
+	TaxableAmount models.Money `json:"taxable_amount"`
+	TaxAmount     models.Money `json:"tax_amount"`
+}
+
+// legacySellerEarnings sums the seller's share of orders placed before carts
+// were split per supplier, which carry no seller. The share is the seller's
+// own items with their tax, computed per rate like an order's tax, so that it
+// is on the same tax-included basis as seller_total. It returns the amounts of
+// completed and of open orders in the seller's currency.
+func legacySellerEarnings(db *gorm.DB, companyID uint) (completed, pending models.Money, err error) {
+	var items []struct {
+		OrderID   uint
+		LineTotal models.Money
+		TaxRate   uint
+		Currency  string
+		Rate      string
+		Target    string
+		Status    string
+	}
+	if err := db.Raw(`
+            SELECT o.id AS order_id, oi.line_total, oi.tax_rate, o.currency,
+                   o.seller_exchange_rate AS rate, o.seller_currency AS target, o.status
+            FROM order_items oi
+            JOIN products p ON oi.product_id = p.id
+            JOIN orders o ON oi.order_id = o.id
+            WHERE oi.deleted_at IS NULL AND COALESCE(o.seller_id, 0) = 0 AND p.supplier_id = ?
+              AND o.status NOT IN ?
+            ORDER BY o.id`,
+		companyID, []string{models.OrderCancelled, models.OrderRejected}).
+		Scan(&items).Error; err != nil {
+		return 0, 0, err
+	}
+	for start := 0; start < len(items); {
+		end := start
+		taxable := map[uint]models.Money{}
+		for ; end < len(items) && items[end].OrderID == items[start].OrderID; end++ {
+			taxable[items[end].TaxRate] += items[end].LineTotal
+		}
+		first := items[start]
+		_, subtotal, tax := computeTaxLines(taxable, first.Currency)
+		amount, err := (subtotal + tax).Convert(first.Rate, first.Target)
+		if err != nil {
+			return 0, 0, err
+		}
+		if first.Status == models.OrderCompleted {
+			completed += amount
+		} else {
+			pending += amount
+		}
+		start = end
+	}
+	return completed, pending, nil
+}
+
+// refundTotals sums the approved refunds of a party, in total and for completed
+// orders. Each refund is converted like the order totals, with the exchange
+// rate of the order and rounded to the minor unit of the party's currency.
+// rateColumn, currencyColumn and partyColumn name the party's columns.
+func refundTotals(db *gorm.DB, rateColumn, currencyColumn, partyColumn string, companyID uint) (total, completed models.Money, err error) {
+	var refunds []struct {
+		RefundAmount models.Money
+		Rate         string
+		Currency     string
+		Status       string
+	}
+	if err := db.Raw(fmt.Sprintf(`
+            SELECT r.refund_amount, %s AS rate, %s AS currency, o.status
+            FROM return_requests r
+            JOIN orders o ON r.order_id = o.id
+            WHERE r.deleted_at IS NULL AND r.status IN ? AND %s = ?`, rateColumn, currencyColumn, partyColumn),
+		[]string{models.ReturnApproved, models.ReturnReceived}, companyID).
+		Scan(&refunds).Error; err != nil {
+		return 0, 0, err
+	}
+	for _, refund := range refunds {
+		amount, err := refund.RefundAmount.Convert(refund.Rate, refund.Currency)
+		if err != nil {
+			return 0, 0, err
+		}
+		total += amount
+		if refund.Status == models.OrderCompleted {
+			completed += amount
+		}
+	}
+	return total, completed, nil
+}
+
+// taxTotals sums the tax lines of a party's completed orders per rate, highest
+// rate first. Each line is converted like the order totals, with the exchange
+// rate of the order and rounded to the minor unit of the party's currency.
+// rateColumn, currencyColumn and partyColumn name the party's columns.
+func taxTotals(db *gorm.DB, rateColumn, currencyColumn, partyColumn string, companyID uint) ([]taxRateTotal, error) {
+	var lines []struct {
+		TaxRate       uint
+		TaxableAmount models.Money
+		TaxAmount     models.Money
+		Rate          string
+		Currency      string
+	}
+	if err := db.Raw(fmt.Sprintf(`
+            SELECT t.tax_rate, t.taxable_amount, t.tax_amount, %s AS rate, %s AS currency
+            FROM order_tax_lines t
+            JOIN orders o ON t.order_id = o.id
+            WHERE o.status = ? AND %s = ?
+            ORDER BY t.tax_rate DESC`, rateColumn, currencyColumn, partyColumn),
+		models.OrderCompleted, companyID).
+		Scan(&lines).Error; err != nil {
+		return nil, err
+	}
+	totals := []taxRateTotal{}
+	for _, line := range lines {
+		taxable, err := line.TaxableAmount.Convert(line.Rate, line.Currency)
+		if err != nil {
+			return nil, err
+		}
+		tax, err := line.TaxAmount.Convert(line.Rate, line.Currency)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(totals); n == 0 || totals[n-1].TaxRate != line.TaxRate {
+			totals = append(totals, taxRateTotal{TaxRate: line.TaxRate})
+		}
+		totals[len(totals)-1].TaxableAmount += taxable
+		totals[len(totals)-1].TaxAmount += tax
+	}
+	return totals, nil
+}
+
 // GetCostDataHandler aggregates cost management data for the authenticated company.
 func GetCostDataHandler(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -24,57 +156,100 @@ func GetCostDataHandler(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		var completedSpent float64
-		var pendingSpent float64
-		var completedEarned float64
-		var pendingEarned float64
+		var completedSpent models.Money
+		var pendingSpent models.Money
+		var completedEarned models.Money
+		var pendingEarned models.Money
+
+		// Purchases are counted in the buyer's currency and sales in the seller's
+		// currency, both at the exchange rate fixed when the order was placed.
 
 		// Query purchase orders (where the company is the buyer).
 		// Completed orders: status "Completed"
 		if err := db.Model(&models.Order{}).
-			Where("company_id = ? AND status = ?", companyID, "Completed").
-			Select("COALESCE(SUM(total),0)").Row().Scan(&completedSpent); err != nil {
+			Where("company_id = ? AND status = ?", companyID, models.OrderCompleted).
+			Select("COALESCE(SUM(buyer_total),0)").Row().Scan(&completedSpent); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate completed spending"})
 			return
 		}
-		// Pending orders: all orders that are not "Completed"
+		// Pending orders: all open orders, i.e. neither completed nor closed.
 		if err := db.Model(&models.Order{}).
-			Where("company_id = ? AND status != ?", companyID, "Completed").
-			Select("COALESCE(SUM(total),0)").Row().Scan(&pendingSpent); err != nil {
+			Where("company_id = ? AND status NOT IN ?", companyID, []string{models.OrderCompleted, models.OrderCancelled, models.OrderRejected}).
+			Select("COALESCE(SUM(buyer_total),0)").Row().Scan(&pendingSpent); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate pending spending"})
 			return
 		}
 
-		// For sales orders, join order items with products.
-		// Completed Earned: sum of (price * quantity) for items where product.SupplierID equals companyID.
-		if err := db.Raw(`
-            SELECT COALESCE(SUM(oi.price * oi.quantity), 0)
-            FROM order_items oi
-            JOIN products p ON oi.product_id = p.id
-            JOIN orders o ON oi.order_id = o.id
-            WHERE p.supplier_id = ? AND o.status = ?`, companyID, "Completed").
+		// Sales are counted at the order total, tax included. Orders placed before
+		// carts were split per supplier carry no seller; see legacySellerEarnings.
+		earnedQuery := `SELECT COALESCE(SUM(seller_total), 0) FROM orders WHERE seller_id = ? AND status %s`
+		// Completed Earned.
+		if err := db.Raw(fmt.Sprintf(earnedQuery, "= ?"), companyID, models.OrderCompleted).
 			Row().Scan(&completedEarned); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate completed earnings"})
 			return
 		}
-		// Pending Earned: for orders not completed.
-		if err := db.Raw(`
-            SELECT COALESCE(SUM(oi.price * oi.quantity), 0)
-            FROM order_items oi
-            JOIN products p ON oi.product_id = p.id
-            JOIN orders o ON oi.order_id = o.id
-            WHERE p.supplier_id = ? AND o.status != ?`, companyID, "Completed").
+		// Pending Earned: for open orders that are neither completed nor closed.
+		closed := []string{models.OrderCompleted, models.OrderCancelled, models.OrderRejected}
+		if err := db.Raw(fmt.Sprintf(earnedQuery, "NOT IN ?"), companyID, closed).
 			Row().Scan(&pendingEarned); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate pending earnings"})
 			return
 		}
+		legacyCompleted, legacyPending, err := legacySellerEarnings(db, companyID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate earnings"})
+			return
+		}
+		completedEarned += legacyCompleted
+		pendingEarned += legacyPending
+
+		// Approved returns credit the buyer back; subtract them from the bucket of
+		// the order they belong to. Returns only exist for delivered or completed orders.
+		refundedSpent, completedRefundedSpent, err := refundTotals(db, "o.exchange_rate", "o.buyer_currency", "r.buyer_id", companyID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate refunds received"})
+			return
+		}
+		refundedEarned, completedRefundedEarned, err := refundTotals(db, "o.seller_exchange_rate", "o.seller_currency", "r.seller_id", companyID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate refunds granted"})
+			return
+		}
+		completedSpent -= completedRefundedSpent
+		pendingSpent -= refundedSpent - completedRefundedSpent
+		completedEarned -= completedRefundedEarned
+		pendingEarned -= refundedEarned - completedRefundedEarned
+
+		// Consumption tax of completed orders per rate, paid as buyer and charged as seller.
+		spentTax, err := taxTotals(db, "o.exchange_rate", "o.buyer_currency", "o.company_id", companyID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate tax paid"})
+			return
+		}
+		earnedTax, err := taxTotals(db, "o.seller_exchange_rate", "o.seller_currency", "o.seller_id", companyID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate tax charged"})
+			return
+		}
+
+		var company models.Companies
+		if err := db.Select("id", "default_currency").First(&company, companyID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch company"})
+			return
+		}
 
 		// Return the aggregated cost data as JSON.
 		c.JSON(http.StatusOK, gin.H{
-			"completedSpent":  completedSpent,
-			"pendingSpent":    pendingSpent,
-			"completedEarned": completedEarned,
-			"pendingEarned":   pendingEarned,
+			"currency":           company.DefaultCurrency,
+			"completedSpent":     completedSpent,
+			"pendingSpent":       pendingSpent,
+			"completedEarned":    completedEarned,
+			"pendingEarned":      pendingEarned,
+			"refundedSpent":      refundedSpent,
+			"refundedEarned":     refundedEarned,
+			"completedSpentTax":  spentTax,
+			"completedEarnedTax": earnedTax,
 		})
 	}
 }
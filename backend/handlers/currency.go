@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"backend/models"
+)
+
+// errNoExchangeRate is returned when the rate table has no entry for a currency pair.
+var errNoExchangeRate = errors.New("no exchange rate")
+
+// validCurrency reports whether code is a supported ISO 4217 currency.
+func validCurrency(code string) bool {
+	_, ok := models.CurrencyDecimals[code]
+	return ok
+}
+
+// lookupExchangeRate returns the current rate converting amounts in from into to.
+// A pair entered the other way round is inverted.
+func lookupExchangeRate(db *gorm.DB, from, to string) (string, error) {
+	if from == to {
+		return "1", nil
+	}
+
+	var entry models.ExchangeRate
+	err := db.Where("(base_currency = ? AND quote_currency = ?) OR (base_currency = ? AND quote_currency = ?)", from, to, to, from).
+		Order("created_at DESC, id DESC").
+		First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", fmt.Errorf("%w from %s to %s", errNoExchangeRate, from, to)
+	}
+	if err != nil {
+		return "", err
+	}
+	if entry.BaseCurrency == from {
+		return entry.Rate, nil
+	}
+
+	rate, err := models.ParseRate(entry.Rate)
+	if err != nil {
+		return "", err
+	}
+	return new(big.Rat).Inv(rate).FloatString(models.ExchangeRateScale), nil
+}
+
+// isPlatformCompany reports whether the company operates the platform, as
+// named by PLATFORM_COMPANY_ID. The exchange-rate table is shared by every
+// company, so only the operator may record rates; with no operator configured
+// nobody can.
+func isPlatformCompany(companyID uint) bool {
+	id, err := strconv.ParseUint(os.Getenv("PLATFORM_COMPANY_ID"), 10, 64)
+	return err == nil && id != 0 && uint(id) == companyID
+}
+
+// convertOrderAmounts sets the buyer's unit prices and the buyer's and seller's
+// totals of a priced and taxed order, at the rates snapshotted on the order and
+// its items. Each amount is rounded once, to the minor unit of its currency.
+func convertOrderAmounts(order *models.Order) error {
+	for i := range order.OrderItems {
+		item := &order.OrderItems[i]
+		buyerPrice, err := item.Price.Convert(item.ExchangeRate, order.BuyerCurrency)
+		if err != nil {
+			return err
+		}
+		item.BuyerPrice = buyerPrice
+	}
+	buyerTotal, err := order.Total.Convert(order.ExchangeRate, order.BuyerCurrency)
+	if err != nil {
+		return err
+	}
+	sellerTotal, err := order.Total.Convert(order.SellerExchangeRate, order.SellerCurrency)
+	if err != nil {
+		return err
+	}
+	order.BuyerTotal = buyerTotal
+	order.SellerTotal = sellerTotal
+	return nil
+}
+
+// exchangeRateCache memoises rate lookups for the duration of one request,
+// so every line converted in a request uses the same rate.
+type exchangeRateCache struct {
+	db    *gorm.DB
+	rates map[[2]string]string
+}
+
+func newExchangeRateCache(db *gorm.DB) *exchangeRateCache {
+	return &exchangeRateCache{db: db, rates: map[[2]string]string{}}
+}
+
+func (c *exchangeRateCache) rate(from, to string) (string, error) {
+	key := [2]string{from, to}
+	if rate, ok := c.rates[key]; ok {
+		return rate, nil
+	}
+	rate, err := lookupExchangeRate(c.db, from, to)
+	if err != nil {
+		return "", err
+	}
+	c.rates[key] = rate
+	return rate, nil
+}
+
+// GetExchangeRatesHandler lists the current rate of every currency pair.
+// With both base and quote query parameters it returns the full history of that pair instead.
+func GetExchangeRatesHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		base := strings.ToUpper(c.Query("base"))
+		quote := strings.ToUpper(c.Query("quote"))
+
+		rates := []models.ExchangeRate{}
+		var err error
+		if base != "" && quote != "" {
+			err = db.Where("base_currency = ? AND quote_currency = ?", base, quote).
+				Order("created_at DESC, id DESC").
+				Find(&rates).Error
+		} else {
+			err = db.Raw(`
+                SELECT DISTINCT ON (base_currency, quote_currency) *
+                FROM exchange_rates
+                ORDER BY base_currency, quote_currency, created_at DESC, id DESC`).
+				Scan(&rates).Error
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exchange rates"})
+			return
+		}
+		c.JSON(http.StatusOK, rates)
+	}
+}
+
+// CreateExchangeRateHandler records a new rate for a currency pair. Earlier
+// entries are kept so the history of a pair can be audited. The rates convert
+// every company's catalog and orders, so only the platform operator records them.
+// Expected JSON body: { "base_currency": "USD", "quote_currency": "JPY", "rate": "149.25" }
+func CreateExchangeRateHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated company id.
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+		if !isPlatformCompany(companyID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the platform operator can record exchange rates"})
+			return
+		}
+
+		var req struct {
+			BaseCurrency  string `json:"base_currency"`
+			QuoteCurrency string `json:"quote_currency"`
+			Rate          string `json:"rate"`
+		}
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+		req.BaseCurrency = strings.ToUpper(strings.TrimSpace(req.BaseCurrency))
+		req.QuoteCurrency = strings.ToUpper(strings.TrimSpace(req.QuoteCurrency))
+		if !validCurrency(req.BaseCurrency) || !validCurrency(req.QuoteCurrency) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency"})
+			return
+		}
+		if req.BaseCurrency == req.QuoteCurrency {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Base and quote currency must differ"})
+			return
+		}
+		rate, err := models.ParseRate(strings.TrimSpace(req.Rate))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Rate must be a positive decimal"})
+			return
+		}
+
+		entry := models.ExchangeRate{
+			BaseCurrency:  req.BaseCurrency,
+			QuoteCurrency: req.QuoteCurrency,
+			Rate:          rate.FloatString(models.ExchangeRateScale),
+			CreatedByID:   companyID,
+			CreatedBy:     c.GetString("email"),
+		}
+		if err := db.Create(&entry).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save exchange rate"})
+			return
+		}
+		c.JSON(http.StatusCreated, entry)
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"testing"
+
+	"backend/models"
+)
+
+func TestConvertOrderAmounts(t *testing.T) {
+	order := models.Order{
+		Currency:           "USD",
+		BuyerCurrency:      "JPY",
+		ExchangeRate:       "149.25",
+		SellerCurrency:     "EUR",
+		SellerExchangeRate: "0.92",
+		Total:              4398,
+		OrderItems: []models.OrderItem{
+			{Price: 1999, ExchangeRate: "149.25"},
+			{Price: 5, ExchangeRate: "149.25"},
+		},
+	}
+	if err := convertOrderAmounts(&order); err != nil {
+		t.Fatalf("convertOrderAmounts: %v", err)
+	}
+
+	// 19.99 * 149.25 = 2983.5075 and 0.05 * 149.25 = 7.4625, rounded to whole yen.
+	for i, want := range []models.Money{models.NewMoney(2984), models.NewMoney(7)} {
+		if got := order.OrderItems[i].BuyerPrice; got != want {
+			t.Errorf("item %d buyer price = %s, want %s", i, got, want)
+		}
+	}
+	// 43.98 * 149.25 = 6564.015 yen; 43.98 * 0.92 = 40.4616 euros.
+	if order.BuyerTotal != models.NewMoney(6564) {
+		t.Errorf("buyer total = %s, want 6564.00", order.BuyerTotal)
+	}
+	if order.SellerTotal != 4046 {
+		t.Errorf("seller total = %s, want 40.46", order.SellerTotal)
+	}
+}
+
+func TestConvertOrderAmountsInvalidRate(t *testing.T) {
+	order := models.Order{
+		BuyerCurrency:      "JPY",
+		ExchangeRate:       "149.25",
+		SellerCurrency:     "USD",
+		SellerExchangeRate: "",
+		Total:              100,
+	}
+	if err := convertOrderAmounts(&order); err == nil {
+		t.Error("convertOrderAmounts succeeded without a seller rate")
+	}
+}
+
+func TestIsPlatformCompany(t *testing.T) {
+	tests := []struct {
+		env       string
+		companyID uint
+		want      bool
+	}{
+		{"7", 7, true},
+		{"7", 8, false},
+		{"", 0, false},
+		{"0", 0, false},
+		{"abc", 0, false},
+	}
+	for _, tt := range tests {
+		t.Setenv("PLATFORM_COMPANY_ID", tt.env)
+		if got := isPlatformCompany(tt.companyID); got != tt.want {
+			t.Errorf("isPlatformCompany(%d) with PLATFORM_COMPANY_ID=%q = %v, want %v", tt.companyID, tt.env, got, tt.want)
+		}
+	}
+}
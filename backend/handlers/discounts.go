@@ -0,0 +1,477 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"backend/models"
+)
+
+// GetPriceBreaksHandler lists the quantity price breaks of a product, lowest quantity first.
+func GetPriceBreaksHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product id"})
+			return
+		}
+
+		var product models.Products
+		if err := db.First(&product, productID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+
+		breaks := []models.PriceBreak{}
+		if err := db.Where("product_id = ?", product.ID).Order("min_quantity").Find(&breaks).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch price breaks"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"currency": product.Currency, "breaks": breaks})
+	}
+}
+
+// SetPriceBreaksHandler replaces the quantity price breaks of one of the company's products.
+// Prices are in the product's currency; an empty list removes every break.
+// Expected JSON body: { "breaks": [{ "min_quantity": 10, "unit_price": "900" }] }
+func SetPriceBreaksHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product id"})
+			return
+		}
+
+		// Get authenticated company id.
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		var req struct {
+			Breaks []struct {
+				MinQuantity uint         `json:"min_quantity"`
+				UnitPrice   models.Money `json:"unit_price"`
+			} `json:"breaks"`
+		}
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+
+		var product models.Products
+		if err := db.First(&product, productID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+		if product.SupplierID != companyID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can only set price breaks on your own products"})
+			return
+		}
+
+		breaks := make([]models.PriceBreak, 0, len(req.Breaks))
+		seen := map[uint]bool{}
+		for _, b := range req.Breaks {
+			if b.MinQuantity < 2 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Minimum quantity must be at least 2"})
+				return
+			}
+			if seen[b.MinQuantity] {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Duplicate minimum quantity"})
+				return
+			}
+			seen[b.MinQuantity] = true
+			if b.UnitPrice <= 0 || b.UnitPrice.Truncate(product.Currency) != b.UnitPrice {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unit price must be a positive amount in " + product.Currency})
+				return
+			}
+			breaks = append(breaks, models.PriceBreak{ProductID: product.ID, MinQuantity: b.MinQuantity, UnitPrice: b.UnitPrice})
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("product_id = ?", product.ID).Delete(&models.PriceBreak{}).Error; err != nil {
+				return err
+			}
+			if len(breaks) == 0 {
+				return nil
+			}
+			return tx.Create(&breaks).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save price breaks"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"currency": product.Currency, "breaks": breaks})
+	}
+}
+
+// couponInput is the payload for creating or updating a coupon.
+type couponInput struct {
+	Code       string       `json:"code"`
+	Type       string       `json:"type"`
+	Percent    uint         `json:"percent"`
+	Amount     models.Money `json:"amount"`
+	Currency   string       `json:"currency"`
+	ValidFrom  *time.Time   `json:"valid_from"`
+	ValidUntil *time.Time   `json:"valid_until"`
+	MaxUses    uint         `json:"max_uses"`
+	Active     *bool        `json:"active"`
+}
+
+// apply validates the input and copies it onto coupon. It returns an error
+// message for the client, or "" on success.
+func (in *couponInput) apply(coupon *models.Coupon) string {
+	code := normalizeCouponCode(in.Code)
+	in.Currency = strings.ToUpper(strings.TrimSpace(in.Currency))
+	if code == "" || len(code) > 50 {
+		return "Code is required and must be at most 50 characters"
+	}
+	switch in.Type {
+	case models.CouponPercent:
+		if in.Percent < 1 || in.Percent > 100 {
+			return "Percent must be between 1 and 100"
+		}
+		in.Amount = 0
+	case models.CouponFixed:
+		if !validCurrency(in.Currency) {
+			return "Unsupported currency"
+		}
+		if in.Amount <= 0 || in.Amount.Truncate(in.Currency) != in.Amount {
+			return "Amount must be a positive amount in " + in.Currency
+		}
+		in.Percent = 0
+	default:
+		return "Type must be percent or fixed"
+	}
+	if in.ValidFrom != nil && in.ValidUntil != nil && !in.ValidUntil.After(*in.ValidFrom) {
+		return "valid_until must be after valid_from"
+	}
+
+	coupon.Code = code
+	coupon.Type = in.Type
+	coupon.Percent = in.Percent
+	coupon.Amount = in.Amount
+	if in.Currency != "" {
+		coupon.Currency = in.Currency
+	}
+	coupon.ValidFrom = in.ValidFrom
+	coupon.ValidUntil = in.ValidUntil
+	coupon.MaxUses = in.MaxUses
+	if in.Active != nil {
+		coupon.Active = *in.Active
+	}
+	return ""
+}
+
+// couponCodeTaken reports whether the seller already has another coupon with the code.
+func couponCodeTaken(db *gorm.DB, sellerID uint, code string, exceptID uint) (bool, error) {
+	var count int64
+	err := db.Model(&models.Coupon{}).
+		Where("seller_id = ? AND code = ? AND id <> ?", sellerID, code, exceptID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetCouponsHandler lists the coupons issued by the authenticated company.
+func GetCouponsHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated company id.
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		coupons := []models.Coupon{}
+		if err := db.Where("seller_id = ?", companyID).Order("created_at DESC").Find(&coupons).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coupons"})
+			return
+		}
+		c.JSON(http.StatusOK, coupons)
+	}
+}
+
+// CreateCouponHandler issues a coupon valid on the authenticated company's products.
+// Expected JSON body:
+// { "code": "SPRING10", "type": "percent", "percent": 10, "valid_until": "2025-06-30T23:59:59Z", "max_uses": 100 }
+// or { "code": "WELCOME", "type": "fixed", "amount": "500", "currency": "JPY" }
+func CreateCouponHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated company id.
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		var req couponInput
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+
+		coupon := models.Coupon{SellerID: companyID, Active: true}
+		if msg := req.apply(&coupon); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		taken, err := couponCodeTaken(db, companyID, coupon.Code, 0)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create coupon"})
+			return
+		}
+		if taken {
+			c.JSON(http.StatusConflict, gin.H{"error": "A coupon with this code already exists"})
+			return
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&coupon).Error; err != nil {
+				return err
+			}
+			// A false Active is a zero value, so Create leaves it to the column default.
+			if !coupon.Active {
+				return tx.Model(&coupon).Update("active", false).Error
+			}
+			return nil
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create coupon"})
+			return
+		}
+		c.JSON(http.StatusCreated, coupon)
+	}
+}
+
+// UpdateCouponHandler replaces the settings of one of the company's coupons.
+// The usage count is kept.
+func UpdateCouponHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		couponID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid coupon id"})
+			return
+		}
+
+		// Get authenticated company id.
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		var req couponInput
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+
+		var coupon models.Coupon
+		if err := db.First(&coupon, couponID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found"})
+			return
+		}
+		if coupon.SellerID != companyID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can only update your own coupons"})
+			return
+		}
+
+		if msg := req.apply(&coupon); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		taken, err := couponCodeTaken(db, companyID, coupon.Code, coupon.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update coupon"})
+			return
+		}
+		if taken {
+			c.JSON(http.StatusConflict, gin.H{"error": "A coupon with this code already exists"})
+			return
+		}
+
+		// used_count is left out so concurrent redemptions are not overwritten.
+		if err := db.Model(&coupon).
+			Select("code", "type", "percent", "amount", "currency", "valid_from", "valid_until", "max_uses", "active").
+			Updates(&coupon).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update coupon"})
+			return
+		}
+		c.JSON(http.StatusOK, coupon)
+	}
+}
+
+// DeleteCouponHandler deletes one of the company's coupons. Orders that used it keep their discount.
+func DeleteCouponHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		couponID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid coupon id"})
+			return
+		}
+
+		// Get authenticated company id.
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		res := db.Where("id = ? AND seller_id = ?", couponID, companyID).Delete(&models.Coupon{})
+		if res.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete coupon"})
+			return
+		}
+		if res.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Coupon deleted"})
+	}
+}
+
+// GetBuyerDiscountsHandler lists the standing discounts the authenticated company grants its buyers.
+func GetBuyerDiscountsHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated company id.
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		type buyerDiscountResponse struct {
+			models.BuyerDiscount
+			BuyerName string `json:"buyer_name"`
+		}
+		discounts := []buyerDiscountResponse{}
+		if err := db.Table("buyer_discounts").
+			Select("buyer_discounts.*, companies.name AS buyer_name").
+			Joins("LEFT JOIN companies ON companies.id = buyer_discounts.buyer_id").
+			Where("buyer_discounts.seller_id = ? AND buyer_discounts.deleted_at IS NULL", companyID).
+			Order("buyer_discounts.buyer_id").
+			Scan(&discounts).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch buyer discounts"})
+			return
+		}
+		c.JSON(http.StatusOK, discounts)
+	}
+}
+
+// SetBuyerDiscountHandler grants a buyer a standing percentage off every order,
+// replacing any discount the buyer already has.
+// Expected JSON body: { "buyer_id": 2, "percent": 5 }
+func SetBuyerDiscountHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated company id.
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		var req struct {
+			BuyerID uint `json:"buyer_id"`
+			Percent uint `json:"percent"`
+		}
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+		if req.BuyerID == 0 || req.BuyerID == companyID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid buyer id"})
+			return
+		}
+		if req.Percent < 1 || req.Percent > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Percent must be between 1 and 100"})
+			return
+		}
+		var buyer models.Companies
+		if err := db.Select("id").First(&buyer, req.BuyerID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Buyer not found"})
+			return
+		}
+
+		discount := models.BuyerDiscount{SellerID: companyID, BuyerID: req.BuyerID}
+		if err := db.Where(&discount).Assign(models.BuyerDiscount{Percent: req.Percent}).FirstOrCreate(&discount).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save buyer discount"})
+			return
+		}
+		c.JSON(http.StatusOK, discount)
+	}
+}
+
+// DeleteBuyerDiscountHandler removes a standing buyer discount granted by the authenticated company.
+func DeleteBuyerDiscountHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		discountID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid buyer discount id"})
+			return
+		}
+
+		// Get authenticated company id.
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		res := db.Where("id = ? AND seller_id = ?", discountID, companyID).Delete(&models.BuyerDiscount{})
+		if res.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete buyer discount"})
+			return
+		}
+		if res.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Buyer discount not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Buyer discount deleted"})
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"backend/models"
+	"backend/utils"
+)
+
+// errInvalidEmailToken is returned when an email token is unknown, used or expired.
+var errInvalidEmailToken = errors.New("invalid or expired token")
+
+// useEmailToken marks the token as used and returns it. Only one of two
+// concurrent uses succeeds.
+func useEmailToken(tx *gorm.DB, token, purpose string) (*models.EmailToken, error) {
+	var emailToken models.EmailToken
+	now := time.Now()
+	if err := tx.Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", utils.HashToken(token), purpose, now).
+		First(&emailToken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errInvalidEmailToken
+		}
+		return nil, err
+	}
+	res := tx.Model(&emailToken).Where("used_at IS NULL").Update("used_at", now)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, errInvalidEmailToken
+	}
+	return &emailToken, nil
+}
+
+// startEmailVerification sends the company a link confirming its email on
+// behalf of the user. The link can be requested again when this fails.
+func startEmailVerification(db *gorm.DB, company *models.Companies, user *models.User) error {
+	token, err := issueEmailToken(db, user, models.TokenVerifyEmail, company.Email, EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	return sendVerificationEmail(company, token)
+}
+
+// VerifyEmailHandler confirms the company's email with the token sent to it.
+// A token sent before the company changed its email no longer counts.
+// Expected JSON body: { "token": "..." }
+func VerifyEmailHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody struct {
+			Token string `json:"token" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A token is required"})
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			emailToken, err := useEmailToken(tx, reqBody.Token, models.TokenVerifyEmail)
+			if err != nil {
+				return err
+			}
+			res := tx.Model(&models.Companies{}).
+				Where("id = ? AND email = ?", emailToken.CompanyID, emailToken.Email).
+				Update("email_verified_at", time.Now())
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return errInvalidEmailToken
+			}
+			return nil
+		})
+		if errors.Is(err, errInvalidEmailToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The link is invalid or has expired"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+	}
+}
+
+// ResendVerificationEmailHandler sends the authenticated company a new
+// verification link; earlier links stop working.
+func ResendVerificationEmailHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c, db)
+		if !ok {
+			return
+		}
+		var company models.Companies
+		if err := db.First(&company, user.CompanyID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+			return
+		}
+		if company.EmailVerifiedAt != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "The email is already verified"})
+			return
+		}
+		if err := startEmailVerification(db, &company, user); err != nil {
+			log.Println("Error sending verification email:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Verification email sent to " + company.Email})
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"backend/mail"
+	"backend/models"
+	"backend/utils"
+)
+
+// Lifetimes of the tokens sent by email.
+const (
+	EmailVerificationTTL = 48 * time.Hour
+	PasswordResetTTL     = time.Hour
+)
+
+// appURL returns the link to a frontend page carrying a token. The frontend
+// is at APP_URL, or else at ALLOWED_ORIGIN.
+func appURL(path, token string) string {
+	base := os.Getenv("APP_URL")
+	if base == "" {
+		base = os.Getenv("ALLOWED_ORIGIN")
+	}
+	if base == "" {
+		base = "http://localhost:3000"
+	}
+	return strings.TrimRight(base, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+// issueEmailToken creates a token for the purpose, replacing the unused ones
+// the user has for it, and returns the token.
+func issueEmailToken(tx *gorm.DB, user *models.User, purpose, email string, ttl time.Duration) (string, error) {
+	token, hash, err := utils.NewToken("")
+	if err != nil {
+		return "", err
+	}
+	if err := tx.Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, purpose).
+		Delete(&models.EmailToken{}).Error; err != nil {
+		return "", err
+	}
+	if err := tx.Create(&models.EmailToken{
+		UserID:    user.ID,
+		CompanyID: user.CompanyID,
+		Purpose:   purpose,
+		Email:     email,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(ttl),
+	}).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// sendVerificationEmail asks the company to confirm its email address.
+func sendVerificationEmail(company *models.Companies, token string) error {
+	return mail.Send(mail.Message{
+		To:      company.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Please confirm the email address of %s by opening this link:\n\n%s\n\n"+
+			"The link is valid for %d hours. Until then your company cannot send permission requests or place orders.\n",
+			company.Name, appURL("/verify-email", token), int(EmailVerificationTTL.Hours())),
+	})
+}
+
+// sendPasswordResetEmail sends the user a link to choose a new password.
+func sendPasswordResetEmail(user *models.User, token string) error {
+	return mail.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Someone asked to reset the password of %s. To choose a new password, open this link:\n\n%s\n\n"+
+			"The link is valid for %d minutes and works once. If you did not ask for it, you can ignore this email.\n",
+			user.Email, appURL("/reset-password", token), int(PasswordResetTTL.Minutes())),
+	})
+}
+
+// sendInvitationEmail invites a person to join the company.
+func sendInvitationEmail(company *models.Companies, invitation *models.UserInvitation, token string) error {
+	return mail.Send(mail.Message{
+		To:      invitation.Email,
+		Subject: fmt.Sprintf("You are invited to join %s", company.Name),
+		Body: fmt.Sprintf("%s invites you to join them as %s. To create your user, open this link:\n\n%s\n\n"+
+			"The invitation is valid until %s.\n",
+			company.Name, invitation.Role, appURL("/invite", token), invitation.ExpiresAt.Format("2006-01-02 15:04 MST")),
+	})
+}
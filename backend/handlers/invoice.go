@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"backend/models"
+	"backend/utils"
+)
+
+// invoiceLine is an order item as printed on an invoice.
+type invoiceLine struct {
+	ProductName string
+	Sku         string
+	Quantity    uint
+	Price       models.Money // effective unit price, after discounts
+	ListPrice   models.Money
+	Amount      models.Money // line total, excluding tax
+	TaxRate     uint
+}
+
+// allocateInvoice returns the invoice of an order, allocating the next number
+// of the seller's sequence the first time it is requested.
+func allocateInvoice(db *gorm.DB, order *models.Order) (models.Invoice, error) {
+	var invoice models.Invoice
+	err := db.Where("order_id = ?", order.ID).First(&invoice).Error
+	if err == nil || !errors.Is(err, gorm.ErrRecordNotFound) {
+		return invoice, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Lock the seller's sequence row; create it on first use.
+		seq := models.InvoiceSequence{SellerID: order.SellerID}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&seq).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&seq, "seller_id = ?", order.SellerID).Error; err != nil {
+			return err
+		}
+
+		// Another request may have issued the invoice while we waited for the lock.
+		err := tx.Where("order_id = ?", order.ID).First(&invoice).Error
+		if err == nil || !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		seq.LastNumber++
+		if err := tx.Model(&seq).Update("last_number", seq.LastNumber).Error; err != nil {
+			return err
+		}
+		invoice = models.Invoice{
+			OrderID:  order.ID,
+			SellerID: order.SellerID,
+			Number:   seq.LastNumber,
+			IssuedAt: time.Now(),
+		}
+		return tx.Create(&invoice).Error
+	})
+	return invoice, err
+}
+
+// invoiceNumber formats an invoice number for display.
+func invoiceNumber(invoice models.Invoice) string {
+	return fmt.Sprintf("INV-%d-%06d", invoice.SellerID, invoice.Number)
+}
+
+// renderInvoicePDF lays out an invoice on A4 pages. The layout follows the
+// qualified invoice requirements: issuer registration number, tax rate per item
+// with reduced-rate items marked, and taxable amount and tax per rate.
+func renderInvoicePDF(invoice models.Invoice, order models.Order, seller, buyer models.Companies, lines []invoiceLine) []byte {
+	doc := utils.NewPDFDocument()
+	const left, right = 50.0, utils.PDFPageWidth - 50
+	y := utils.PDFPageHeight - 60
+
+	doc.Text(left, y, 22, utils.FontBold, "INVOICE")
+	doc.TextRight(right, y, 10, invoiceNumber(invoice))
+	y -= 16
+	doc.TextRight(right, y, 10, "Issued "+invoice.IssuedAt.Format("2006-01-02"))
+	y -= 14
+	doc.TextRight(right, y, 10, fmt.Sprintf("Order #%d of %s", order.ID, order.Date.Format("2006-01-02")))
+
+	// Seller and buyer blocks side by side.
+	y -= 36
+	party := func(x float64, title string, company models.Companies) {
+		py := y
+		doc.Text(x, py, 10, utils.FontBold, title)
+		lines := []string{company.Name, company.Address, company.Phone, company.Email}
+		if company.RegistrationNumber != "" {
+			lines = append(lines, "Registration No. "+company.RegistrationNumber)
+		}
+		for _, line := range lines {
+			py -= 14
+			doc.Text(x, py, 10, utils.FontRegular, line)
+		}
+	}
+	party(left, "From", seller)
+	party(utils.PDFPageWidth/2, "Bill to", buyer)
+	y -= 104
+
+	header := func() {
+		doc.Text(left, y, 10, utils.FontBold, "Item")
+		doc.Text(left+200, y, 10, utils.FontBold, "SKU")
+		doc.TextRight(right-210, y, 10, "Tax")
+		doc.TextRight(right-170, y, 10, "Qty")
+		doc.TextRight(right-80, y, 10, "Unit price")
+		doc.TextRight(right, y, 10, "Amount")
+		y -= 6
+		doc.Line(left, y, right, y)
+		y -= 16
+	}
+	header()
+
+	reduced := false
+	for _, line := range lines {
+		if y < 100 {
+			doc.AddPage()
+			y = utils.PDFPageHeight - 60
+			header()
+		}
+		name := line.ProductName
+		if line.TaxRate == models.TaxRates[models.TaxCategoryReduced] {
+			name += " *"
+			reduced = true
+		}
+		doc.Text(left, y, 10, utils.FontRegular, name)
+		doc.Text(left+200, y, 10, utils.FontRegular, line.Sku)
+		doc.TextRight(right-210, y, 10, fmt.Sprintf("%d%%", line.TaxRate))
+		doc.TextRight(right-170, y, 10, strconv.FormatUint(uint64(line.Quantity), 10))
+		doc.TextRight(right-80, y, 10, line.Price.String())
+		doc.TextRight(right, y, 10, line.Amount.String())
+		y -= 16
+		if discount := line.ListPrice.Mul(line.Quantity) - line.Amount; line.ListPrice > 0 && discount > 0 {
+			doc.Text(left+10, y, 9, utils.FontRegular, fmt.Sprintf("List price %s, discount -%s", line.ListPrice, discount))
+			y -= 14
+		}
+	}
+
+	doc.Line(left, y+8, right, y+8)
+	y -= 8
+	if reduced {
+		doc.Text(left, y, 9, utils.FontRegular, "* Reduced tax rate item")
+	}
+
+	// Totals: subtotal, then taxable amount and tax per rate, then the grand total.
+	if y < 100+14*float64(len(order.TaxLines)+2) {
+		doc.AddPage()
+		y = utils.PDFPageHeight - 60
+	}
+	doc.Text(right-250, y, 10, utils.FontRegular, "Subtotal")
+	doc.TextRight(right, y, 10, order.Subtotal.String())
+	for _, tl := range order.TaxLines {
+		y -= 14
+		doc.Text(right-250, y, 10, utils.FontRegular, fmt.Sprintf("%d%% taxable %s, tax", tl.TaxRate, tl.TaxableAmount))
+		doc.TextRight(right, y, 10, tl.TaxAmount.String())
+	}
+	y -= 18
+	doc.Text(right-250, y, 11, utils.FontBold, fmt.Sprintf("Total (%s, tax included)", order.Currency))
+	doc.TextRight(right, y, 11, order.Total.String())
+
+	return doc.Bytes()
+}
+
+// GetInvoicePDFHandler renders the invoice of a completed order as a PDF.
+// The invoice number is allocated on first download and stays fixed afterwards.
+// Only the buyer and the seller of the order can download it.
+func GetInvoicePDFHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idStr := c.Param("id")
+		orderID, err := strconv.Atoi(idStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order id"})
+			return
+		}
+
+		// Get authenticated company id.
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		var order models.Order
+		if err := db.Preload("OrderItems.Discounts").Preload("TaxLines").First(&order, orderID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+			return
+		}
+		if order.CompanyID != companyID && !isOrderSeller(db, &order, companyID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+		if order.Status != models.OrderCompleted {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invoices are only available for completed orders"})
+			return
+		}
+		if order.SellerID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invoices are only available for single-seller orders"})
+			return
+		}
+
+		// Companies may have been deleted since; invoices must still render.
+		var seller, buyer models.Companies
+		if err := db.Unscoped().First(&seller, order.SellerID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch seller"})
+			return
+		}
+		if err := db.Unscoped().First(&buyer, order.CompanyID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch buyer"})
+			return
+		}
+
+		lines := make([]invoiceLine, 0, len(order.OrderItems))
+		for _, item := range order.OrderItems {
+			var product models.Products
+			if err := db.Unscoped().First(&product, item.ProductID).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch product"})
+				return
+			}
+			lines = append(lines, invoiceLine{
+				ProductName: product.ProductName,
+				Sku:         product.Sku,
+				Quantity:    item.Quantity,
+				Price:       item.Price,
+				ListPrice:   item.ListPrice,
+				Amount:      item.LineTotal,
+				TaxRate:     item.TaxRate,
+			})
+		}
+
+		// Orders placed before tax was introduced have no stored breakdown.
+		if len(order.TaxLines) == 0 {
+			applyOrderTax(&order)
+		}
+
+		invoice, err := allocateInvoice(db, &order)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to allocate invoice number"})
+			return
+		}
+
+		pdf := renderInvoicePDF(invoice, order, seller, buyer, lines)
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, invoiceNumber(invoice)))
+		c.Data(http.StatusOK, "application/pdf", pdf)
+	}
+}
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/signing"
+)
+
+// JWKSHandler publishes the public keys verifying the API's JWTs, so that
+// other services can verify them. A token whose kid is missing from a cached
+// copy was signed with a new key; the set should then be fetched again.
+func JWKSHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, signing.PublicKeys())
+	}
+}
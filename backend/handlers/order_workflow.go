@@ -0,0 +1,391 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"backend/models"
+)
+
+// errOrderStatusChanged is returned when an order left the expected status
+// between loading it and updating it.
+var errOrderStatusChanged = errors.New("order status changed")
+
+// errGuardQuery wraps the database errors of a transition Guard, which are not
+// the caller's fault unlike the other errors a Guard returns.
+var errGuardQuery = errors.New("guard query failed")
+
+// orderParty identifies which side of an order may fire a transition.
+type orderParty string
+
+const (
+	partyBuyer  orderParty = "buyer"
+	partySeller orderParty = "seller"
+)
+
+// transitionContext carries the data available to guards and effects of a transition.
+type transitionContext struct {
+	Order      *models.Order
+	CompanyID  uint
+	ActorEmail string
+	Comment    string
+	At         time.Time
+}
+
+// orderTransition declares one allowed move in the order workflow.
+type orderTransition struct {
+	Action string // URL action: PUT /api/orders/:id/<action>/
+	From   []string
+	To     string
+	Party  orderParty
+	// Automatic transitions are fired by the server (e.g. when a shipment is
+	// recorded) and get no route of their own.
+	Automatic bool
+	// Guard validates the request before anything is written.
+	// Its error message is returned to the client as a 400.
+	Guard func(db *gorm.DB, tc *transitionContext) error
+	// Fields returns extra order columns to set together with the status.
+	Fields func(tc *transitionContext) map[string]interface{}
+	// Effect runs inside the transaction after the status has changed.
+	Effect func(tx *gorm.DB, tc *transitionContext) error
+}
+
+// orderWorkflow is the complete order state machine. Adding a state only
+// requires declaring its transitions here; routes are registered from this table.
+var orderWorkflow = []orderTransition{
+	{
+		// Accepted orders keep their reservations until shipments are recorded.
+		Action: "accept",
+		From:   []string{models.OrderPending},
+		To:     models.OrderProcessing,
+		Party:  partySeller,
+	},
+	{
+		Action:    "ship_partial",
+		From:      []string{models.OrderProcessing, models.OrderPartiallyShipped},
+		To:        models.OrderPartiallyShipped,
+		Party:     partySeller,
+		Automatic: true,
+	},
+	{
+		Action:    "ship",
+		From:      []string{models.OrderProcessing, models.OrderPartiallyShipped},
+		To:        models.OrderShipped,
+		Party:     partySeller,
+		Automatic: true,
+	},
+	{
+		// Processing is accepted for orders shipped before shipments were tracked.
+		Action: "deliver",
+		From:   []string{models.OrderShipped, models.OrderProcessing},
+		To:     models.OrderDelivered,
+		Party:  partyBuyer,
+		Guard: func(db *gorm.DB, tc *transitionContext) error {
+			var unshipped int64
+			if err := db.Model(&models.OrderItem{}).
+				Where("order_id = ? AND quantity_shipped < quantity", tc.Order.ID).
+				Count(&unshipped).Error; err != nil {
+				return fmt.Errorf("%w: %v", errGuardQuery, err)
+			}
+			if unshipped > 0 {
+				return errors.New("Order has not been fully shipped yet")
+			}
+			return nil
+		},
+	},
+	{
+		Action: "complete",
+		From:   []string{models.OrderDelivered},
+		To:     models.OrderCompleted,
+		Party:  partySeller,
+	},
+	{
+		Action: "cancel",
+		From:   []string{models.OrderPending},
+		To:     models.OrderCancelled,
+		Party:  partyBuyer,
+		Fields: func(tc *transitionContext) map[string]interface{} {
+			return map[string]interface{}{
+				"cancelled_at":        tc.At,
+				"cancelled_by":        tc.ActorEmail,
+				"cancellation_reason": tc.Comment,
+			}
+		},
+		Effect: func(tx *gorm.DB, tc *transitionContext) error {
+			return releaseOrderReservations(tx, tc.Order.ID)
+		},
+	},
+	{
+		Action: "reject",
+		From:   []string{models.OrderPending},
+		To:     models.OrderRejected,
+		Party:  partySeller,
+		Guard: func(db *gorm.DB, tc *transitionContext) error {
+			if tc.Comment == "" {
+				return errors.New("A rejection reason is required")
+			}
+			return nil
+		},
+		Fields: func(tc *transitionContext) map[string]interface{} {
+			return map[string]interface{}{
+				"rejected_at":      tc.At,
+				"rejected_by_id":   tc.CompanyID,
+				"rejected_by":      tc.ActorEmail,
+				"rejection_reason": tc.Comment,
+			}
+		},
+		Effect: func(tx *gorm.DB, tc *transitionContext) error {
+			return releaseOrderReservations(tx, tc.Order.ID)
+		},
+	},
+}
+
+// OrderActions returns the client-fired actions of the order workflow taken by
+// the seller, or else by the buyer, in declaration order.
+func OrderActions(seller bool) []string {
+	party := partyBuyer
+	if seller {
+		party = partySeller
+	}
+	actions := make([]string, 0, len(orderWorkflow))
+	for _, t := range orderWorkflow {
+		if !t.Automatic && t.Party == party {
+			actions = append(actions, t.Action)
+		}
+	}
+	return actions
+}
+
+// findOrderTransition returns the declared transition for an action, or nil.
+func findOrderTransition(action string) *orderTransition {
+	for i := range orderWorkflow {
+		if orderWorkflow[i].Action == action {
+			return &orderWorkflow[i]
+		}
+	}
+	return nil
+}
+
+// applyOrderTransition moves tc.Order from its current status to t.To, records
+// the history entry and runs the transition's effect. It must run inside a transaction.
+func applyOrderTransition(tx *gorm.DB, t *orderTransition, tc *transitionContext) error {
+	fields := map[string]interface{}{}
+	if t.Fields != nil {
+		fields = t.Fields(tc)
+	}
+	fields["status"] = t.To
+
+	// The status condition guards against concurrent transitions.
+	from := tc.Order.Status
+	res := tx.Model(&models.Order{}).
+		Where("id = ? AND status = ?", tc.Order.ID, from).
+		Updates(fields)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errOrderStatusChanged
+	}
+	if err := recordOrderStatus(tx, tc.Order.ID, t.Action, from, t.To, tc.CompanyID, tc.ActorEmail, tc.Comment); err != nil {
+		return err
+	}
+	tc.Order.Status = t.To
+	if t.Effect != nil {
+		return t.Effect(tx, tc)
+	}
+	return nil
+}
+
+// isOrderSeller reports whether the company is the seller of the order.
+// Orders placed before carts were split per supplier carry no seller id;
+// for those any company supplying a product in the order counts as seller.
+func isOrderSeller(db *gorm.DB, order *models.Order, companyID uint) bool {
+	if order.SellerID != 0 {
+		return order.SellerID == companyID
+	}
+	var sellerProductCount int64
+	db.Table("order_items").
+		Joins("JOIN products ON products.id = order_items.product_id").
+		Where("order_items.order_id = ? AND products.supplier_id = ?", order.ID, companyID).
+		Count(&sellerProductCount)
+	return sellerProductCount > 0
+}
+
+// recordOrderStatus appends an entry to the order's status history.
+func recordOrderStatus(tx *gorm.DB, orderID uint, action, from, to string, companyID uint, email, comment string) error {
+	return tx.Create(&models.OrderStatusHistory{
+		OrderID:        orderID,
+		Action:         action,
+		FromStatus:     from,
+		ToStatus:       to,
+		ActorCompanyID: companyID,
+		ActorEmail:     email,
+		Comment:        comment,
+	}).Error
+}
+
+// OrderTransitionHandler fires the named workflow action on an order.
+// Expected JSON body (optional): { "comment": "..." } ("reason" is accepted as an alias).
+func OrderTransitionHandler(db *gorm.DB, action string) gin.HandlerFunc {
+	t := findOrderTransition(action)
+	if t == nil || t.Automatic {
+		panic("unknown order action: " + action)
+	}
+
+	return func(c *gin.Context) {
+		idStr := c.Param("id")
+		orderID, err := strconv.Atoi(idStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order id"})
+			return
+		}
+
+		// Get authenticated company id.
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		var req struct {
+			Comment string `json:"comment"`
+			Reason  string `json:"reason"`
+		}
+		// The body is optional.
+		if c.Request.ContentLength > 0 {
+			if err := c.BindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+				return
+			}
+		}
+		comment := strings.TrimSpace(req.Comment)
+		if comment == "" {
+			comment = strings.TrimSpace(req.Reason)
+		}
+
+		var order models.Order
+		if err := db.First(&order, orderID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+			return
+		}
+
+		// Verify the caller is the party allowed to fire this transition.
+		allowed := false
+		switch t.Party {
+		case partyBuyer:
+			allowed = order.CompanyID == companyID
+		case partySeller:
+			allowed = isOrderSeller(db, &order, companyID)
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Only the %s can %s this order", t.Party, t.Action)})
+			return
+		}
+
+		notInState := fmt.Sprintf("Order is not in %s state", strings.ToLower(strings.Join(t.From, " or ")))
+		fromOK := false
+		for _, from := range t.From {
+			if order.Status == from {
+				fromOK = true
+			}
+		}
+		if !fromOK {
+			c.JSON(http.StatusBadRequest, gin.H{"error": notInState})
+			return
+		}
+
+		tc := &transitionContext{
+			Order:      &order,
+			CompanyID:  companyID,
+			ActorEmail: c.GetString("email"),
+			Comment:    comment,
+			At:         time.Now(),
+		}
+		if t.Guard != nil {
+			if err := t.Guard(db, tc); err != nil {
+				if errors.Is(err, errGuardQuery) {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status"})
+					return
+				}
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			return applyOrderTransition(tx, t, tc)
+		})
+		if errors.Is(err, errOrderStatusChanged) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": notInState})
+			return
+		}
+		if errors.Is(err, errInsufficientStock) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Insufficient stock for this order"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status"})
+			return
+		}
+
+		if err := db.Preload("OrderItems.Discounts").Preload("TaxLines").First(&order, order.ID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reload order"})
+			return
+		}
+		c.JSON(http.StatusOK, order)
+	}
+}
+
+// GetOrderHistoryHandler returns the status transitions of an order.
+// Only the buyer and the sellers of the order can view it.
+func GetOrderHistoryHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idStr := c.Param("id")
+		orderID, err := strconv.Atoi(idStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order id"})
+			return
+		}
+
+		// Get authenticated company id.
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		var order models.Order
+		if err := db.First(&order, orderID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+			return
+		}
+		if order.CompanyID != companyID && !isOrderSeller(db, &order, companyID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+
+		var history []models.OrderStatusHistory
+		if err := db.Where("order_id = ?", order.ID).Order("created_at, id").Find(&history).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch order history"})
+			return
+		}
+		c.JSON(http.StatusOK, history)
+	}
+}
@@ -1,8 +1,9 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -11,6 +12,72 @@ import (
 	"backend/models"
 )
 
+// Reasons a cart line cannot be ordered.
+const (
+	lineInvalidQuantity = "invalid_quantity"
+	lineNotFound        = "not_found"
+	lineDeleted         = "deleted"
+	lineOwnProduct      = "own_product"
+	lineInactive        = "inactive"
+	lineNotPermitted    = "not_permitted"
+	lineNotShared       = "not_shared"
+)
+
+var orderLineMessages = map[string]string{
+	lineDeleted:      "Product is no longer available",
+	lineOwnProduct:   "Cannot order your own product",
+	lineInactive:     "Product is not active",
+	lineNotPermitted: "Supplier has not granted your company access",
+	lineNotShared:    "Product is not shared with your company",
+}
+
+// orderLineError explains why one line of a cart cannot be ordered.
+type orderLineError struct {
+	Index     int    `json:"index"` // position in the request's items
+	ProductID uint   `json:"product_id"`
+	Reason    string `json:"reason"`
+	Message   string `json:"message"`
+}
+
+// orderableReason returns why the buyer cannot order the product, or "" if it can.
+// The buyer needs a permitted request with the supplier that shares the product.
+func orderableReason(db *gorm.DB, buyerID uint, product *models.Products) (string, error) {
+	switch {
+	case product.DeletedAt.Valid:
+		return lineDeleted, nil
+	case product.SupplierID == buyerID:
+		return lineOwnProduct, nil
+	case product.Status != models.ProductActive:
+		return lineInactive, nil
+	}
+
+	var grants int64
+	if err := db.Model(&models.PermissionRequest{}).
+		Where("seller_id = ? AND requester_id = ?", product.SupplierID, buyerID).
+		Where(activeGrantSQL).
+		Count(&grants).Error; err != nil {
+		return "", err
+	}
+	if grants == 0 {
+		return lineNotPermitted, nil
+	}
+	shared, err := productShared(db, buyerID, product.ID)
+	if err != nil {
+		return "", err
+	}
+	if !shared {
+		return lineNotShared, nil
+	}
+	return "", nil
+}
+
+// CreateOrderHandler places the buyer's cart. Items from different suppliers are
+// split into one order per supplier, grouped under a single Checkout.
+// Prices are resolved server-side: the buyer's price list, quantity price
+// breaks, the buyer's standing discount and at most one coupon per supplier.
+// Lines the buyer may not order are rejected with a per-line "items" list.
+// Expected JSON body:
+// { "items": [{ "product_id": 1, "quantity": 2 }], "coupon_codes": ["SPRING10"] }
 func CreateOrderHandler(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req struct {
@@ -18,6 +85,7 @@ func CreateOrderHandler(db *gorm.DB) gin.HandlerFunc {
 				ProductID uint `json:"product_id"`
 				Quantity  uint `json:"quantity"`
 			} `json:"items"`
+			CouponCodes []string `json:"coupon_codes"`
 		}
 
 		if err := c.BindJSON(&req); err != nil {
@@ -37,201 +105,201 @@ func CreateOrderHandler(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		total := 0.0
-		orderItems := []models.OrderItem{}
+		if len(req.Items) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "At least one item is required"})
+			return
+		}
+
+		var buyer models.Companies
+		if err := db.Select("id", "default_currency").First(&buyer, companyID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch company"})
+			return
+		}
 
-		// Loop through each item from the payload.
-		for _, item := range req.Items {
-			if item.ProductID == 0 {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product id"})
-				return
+		// Split the cart into one order per supplier and price currency, keeping
+		// the cart order. The checkout total is in the buyer's currency.
+		checkout := models.Checkout{CompanyID: companyID, Currency: buyer.DefaultCurrency}
+		type orderKey struct {
+			sellerID uint
+			currency string
+		}
+		orderBySeller := map[orderKey]int{}
+		rates := newExchangeRateCache(db)
+		sellerCurrencies := map[uint]string{}
+		now := time.Now()
+
+		// Loop through each item from the payload. Lines that cannot be ordered
+		// are collected so the buyer sees all of them at once.
+		var lineErrors []orderLineError
+		for i, item := range req.Items {
+			if item.Quantity == 0 {
+				lineErrors = append(lineErrors, orderLineError{i, item.ProductID, lineInvalidQuantity, "Quantity must be greater than zero"})
+				continue
 			}
 
 			var product models.Products
-			if err := db.First(&product, item.ProductID).Error; err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Product not found"})
+			if err := db.Unscoped().First(&product, item.ProductID).Error; err != nil {
+				lineErrors = append(lineErrors, orderLineError{i, item.ProductID, lineNotFound, "Product not found"})
+				continue
+			}
+			reason, err := orderableReason(db, companyID, &product)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check product access"})
 				return
 			}
+			if reason != "" {
+				lineErrors = append(lineErrors, orderLineError{i, item.ProductID, reason, orderLineMessages[reason]})
+				continue
+			}
 
-			// Check that the product's supplier is not the buyer's company.
-			if product.SupplierID == companyID {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot order your own product"})
+			// The rate is snapshotted so later rate updates never change the order.
+			rate, err := rates.rate(product.Currency, buyer.DefaultCurrency)
+			if errors.Is(err, errNoExchangeRate) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("No exchange rate from %s to %s for product %d", product.Currency, buyer.DefaultCurrency, product.ID)})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exchange rate"})
 				return
 			}
 
-			subtotal := product.Price * float64(item.Quantity)
-			total += subtotal
-
-			orderItems = append(orderItems, models.OrderItem{
-				ProductID: item.ProductID,
-				Quantity:  item.Quantity,
-				Price:     product.Price,
+			key := orderKey{product.SupplierID, product.Currency}
+			idx, found := orderBySeller[key]
+			if !found {
+				sellerCurrency, found := sellerCurrencies[product.SupplierID]
+				if !found {
+					var seller models.Companies
+					if err := db.Unscoped().Select("id", "default_currency").First(&seller, product.SupplierID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch supplier"})
+						return
+					}
+					sellerCurrency = seller.DefaultCurrency
+					sellerCurrencies[product.SupplierID] = sellerCurrency
+				}
+				sellerRate, err := rates.rate(product.Currency, sellerCurrency)
+				if errors.Is(err, errNoExchangeRate) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("No exchange rate from %s to %s for product %d", product.Currency, sellerCurrency, product.ID)})
+					return
+				}
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exchange rate"})
+					return
+				}
+
+				idx = len(checkout.Orders)
+				orderBySeller[key] = idx
+				checkout.Orders = append(checkout.Orders, models.Order{
+					CompanyID:          companyID,
+					SellerID:           product.SupplierID,
+					Currency:           product.Currency,
+					BuyerCurrency:      buyer.DefaultCurrency,
+					ExchangeRate:       rate,
+					SellerCurrency:     sellerCurrency,
+					SellerExchangeRate: sellerRate,
+					Date:               now,
+					Status:             models.OrderPending, // New orders start with "Pending" status
+				})
+			}
+			order := &checkout.Orders[idx]
+
+			order.OrderItems = append(order.OrderItems, models.OrderItem{
+				ProductID:    item.ProductID,
+				Quantity:     item.Quantity,
+				ListPrice:    product.Price,
+				TaxRate:      taxRateOf(product),
+				ExchangeRate: rate,
 			})
 		}
 
-		order := models.Order{
-			CompanyID:  companyID,
-			Total:      total,
-			Date:       time.Now(),
-			OrderItems: orderItems,
-			Status:     "Pending", // New orders start with "Pending" status
-		}
-
-		if err := db.Transaction(func(tx *gorm.DB) error {
-			return tx.Create(&order).Error
-		}); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order"})
-			return
-		}
-
-		c.JSON(http.StatusCreated, order)
-	}
-}
-
-// GetOrdersHandler retrieves all orders for the authenticated company.
-// Here we assume that the authenticated company id has been set in the context (under "companyID").
-func GetOrdersHandler(db *gorm.DB) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		companyIDVal, exists := c.Get("companyID")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-			return
-		}
-		companyID, ok := companyIDVal.(uint)
-		if !ok {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+		if len(lineErrors) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Some items cannot be ordered", "items": lineErrors})
 			return
 		}
 
-		var orders []models.Order
-		// Preload OrderItems so that order details are included.
-		if err := db.Preload("OrderItems").Where("company_id = ?", companyID).Find(&orders).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve orders"})
+		discounts, err := loadOrderDiscounts(db, companyID, checkout.Orders, req.CouponCodes, now)
+		var couponErr *couponError
+		if errors.As(err, &couponErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": couponErr.Message})
 			return
 		}
-		c.JSON(http.StatusOK, orders)
-	}
-}
-
-// AcceptOrderHandler updates a pending order’s status to "processing".
-// Only the seller (who owns products in the order) can accept.
-func AcceptOrderHandler(db *gorm.DB) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		orderID, err := strconv.Atoi(idStr)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order id"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve discounts"})
 			return
 		}
 
-		// Get authenticated company id.
-		companyIDVal, exists := c.Get("companyID")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-			return
-		}
-		companyID, ok := companyIDVal.(uint)
-		if !ok {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
-			return
-		}
-
-		var order models.Order
-		if err := db.Preload("OrderItems").First(&order, orderID).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
-			return
+		// Tax is rounded per order since each seller issues its own invoice.
+		for i := range checkout.Orders {
+			order := &checkout.Orders[i]
+			priceOrderItems(order, discounts.priceLists[order.SellerID], discounts.breaks, discounts.buyerPercent[order.SellerID], discounts.couponFor(order))
+			applyOrderTax(order)
+			if err := convertOrderAmounts(order); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to convert order total"})
+				return
+			}
+			checkout.Total += order.BuyerTotal
 		}
 
-		// Verify that the authenticated user is the seller (owns at least one product in the order).
-		var sellerProductCount int64
-		db.Table("order_items").
-			Joins("JOIN products ON products.id = order_items.product_id").
-			Where("order_items.order_id = ? AND products.supplier_id = ?", orderID, companyID).
-			Count(&sellerProductCount)
-		if sellerProductCount == 0 {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Only the seller can accept this order"})
-			return
-		}
+		// Create the orders and reserve their stock atomically so concurrent
+		// buyers cannot oversell the same units.
+		err = db.Transaction(func(tx *gorm.DB) error {
+			for _, coupon := range discounts.coupons {
+				if err := redeemCoupon(tx, coupon.ID); err != nil {
+					return err
+				}
+			}
 
-		// Only allow update if current status is "Pending"
-		if order.Status != "Pending" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Order is not in pending state"})
+			orders := checkout.Orders
+			checkout.Orders = nil
+			if err := tx.Create(&checkout).Error; err != nil {
+				return err
+			}
+			checkout.Orders = orders
+
+			var shortages []stockShortage
+			for i := range checkout.Orders {
+				order := &checkout.Orders[i]
+				order.CheckoutID = &checkout.ID
+				if err := tx.Create(order).Error; err != nil {
+					return err
+				}
+				if err := recordOrderStatus(tx, order.ID, "create", "", order.Status, companyID, c.GetString("email"), ""); err != nil {
+					return err
+				}
+				// Keep going on shortages so the buyer sees every line that cannot be covered.
+				var shortage *insufficientStockError
+				if err := reserveOrderStock(tx, order); errors.As(err, &shortage) {
+					shortages = append(shortages, shortage.Shortages...)
+				} else if err != nil {
+					return err
+				}
+			}
+			if len(shortages) > 0 {
+				return &insufficientStockError{Shortages: shortages}
+			}
+			return nil
+		})
+		var shortage *insufficientStockError
+		if errors.As(err, &shortage) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Insufficient stock", "items": shortage.Shortages})
 			return
 		}
-
-		order.Status = "Processing"
-		if err := db.Save(&order).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status"})
+		if errors.Is(err, errCouponExhausted) {
+			c.JSON(http.StatusConflict, gin.H{"error": "A coupon reached its usage limit, please retry without it"})
 			return
 		}
-
-		c.JSON(http.StatusOK, order)
-	}
-}
-
-// DeliverOrderHandler marks an order as delivered.
-// Only the buyer (order owner) can mark it as delivered.
-func DeliverOrderHandler(db *gorm.DB) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		orderID, err := strconv.Atoi(idStr)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order id"})
-			return
-		}
-
-		// Get authenticated company id.
-		companyIDVal, exists := c.Get("companyID")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-			return
-		}
-		companyID, ok := companyIDVal.(uint)
-		if !ok {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
-			return
-		}
-
-		var order models.Order
-		if err := db.First(&order, orderID).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
-			return
-		}
-
-		// Verify that the authenticated user is the buyer.
-		if order.CompanyID != companyID {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Only the buyer can mark this order as delivered"})
-			return
-		}
-
-		// Allow only orders in processing state to be marked delivered.
-		if order.Status != "Processing" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Order is not in processing state"})
-			return
-		}
-
-		order.Status = "Delivered"
-		if err := db.Save(&order).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order"})
 			return
 		}
 
-		c.JSON(http.StatusOK, order)
+		c.JSON(http.StatusCreated, checkout)
 	}
 }
 
-// CompleteOrderHandler marks an order as completed.
-// Only the seller (who owns products in the order) can complete it.
-func CompleteOrderHandler(db *gorm.DB) gin.HandlerFunc {
+// GetOrdersHandler retrieves all orders for the authenticated company.
+// Here we assume that the authenticated company id has been set in the context (under "companyID").
+func GetOrdersHandler(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		orderID, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order id"})
-			return
-		}
-
-		// Get authenticated company id.
 		companyIDVal, exists := c.Get("companyID")
 		if !exists {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
@@ -243,35 +311,12 @@ func CompleteOrderHandler(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		var order models.Order
-		if err := db.First(&order, orderID).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
-			return
-		}
-
-		// Verify that the authenticated user is the seller.
-		var sellerProductCount int64
-		db.Table("order_items").
-			Joins("JOIN products ON products.id = order_items.product_id").
-			Where("order_items.order_id = ? AND products.supplier_id = ?", orderID, companyID).
-			Count(&sellerProductCount)
-		if sellerProductCount == 0 {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Only the seller can complete this order"})
-			return
-		}
-
-		// Allow only orders in Delivered state to be completed.
-		if order.Status != "Delivered" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Order is not in delivered state"})
-			return
-		}
-
-		order.Status = "Completed"
-		if err := db.Save(&order).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status"})
+		var orders []models.Order
+		// Preload OrderItems so that order details are included.
+		if err := db.Preload("OrderItems.Discounts").Preload("TaxLines").Where("company_id = ?", companyID).Find(&orders).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve orders"})
 			return
 		}
-
-		c.JSON(http.StatusOK, order)
+		c.JSON(http.StatusOK, orders)
 	}
 }
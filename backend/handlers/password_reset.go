@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"backend/models"
+)
+
+// passwordResetInterval is how often at most a user is sent a reset link.
+const passwordResetInterval = time.Minute
+
+// ForgotPasswordHandler emails a password reset link to an active user. The
+// response is the same whether or not the email belongs to a user.
+// Expected JSON body: { "email": "..." }
+func ForgotPasswordHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody struct {
+			Email string `json:"email" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "An email is required"})
+			return
+		}
+		response := gin.H{"message": "If the email belongs to a user, a link to reset the password has been sent"}
+
+		var user models.User
+		err := db.Joins("JOIN companies ON companies.id = users.company_id AND companies.deleted_at IS NULL AND companies.status = 'active'").
+			Where("users.email = ? AND users.status = ?", strings.TrimSpace(reqBody.Email), models.UserActive).
+			First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusOK, response)
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+			return
+		}
+
+		// Do not flood the mailbox with links.
+		var recent int64
+		if err := db.Model(&models.EmailToken{}).
+			Where("user_id = ? AND purpose = ? AND created_at > ?", user.ID, models.TokenResetPassword, time.Now().Add(-passwordResetInterval)).
+			Count(&recent).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+			return
+		}
+		if recent > 0 {
+			c.JSON(http.StatusOK, response)
+			return
+		}
+
+		token, err := issueEmailToken(db, &user, models.TokenResetPassword, user.Email, PasswordResetTTL)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+			return
+		}
+		if err := sendPasswordResetEmail(&user, token); err != nil {
+			log.Println("Error sending password reset email:", err)
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// ResetPasswordHandler sets a new password with the token of a reset link.
+// The token works once, and every session of the user ends.
+// Expected JSON body: { "token": "...", "password": "..." }
+func ResetPasswordHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody struct {
+			Token    string `json:"token" binding:"required"`
+			Password string `json:"password" binding:"required,min=8"`
+		}
+		if err := c.ShouldBindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A token and a password of at least 8 characters are required"})
+			return
+		}
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(reqBody.Password), bcrypt.DefaultCost)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+			return
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			emailToken, err := useEmailToken(tx, reqBody.Token, models.TokenResetPassword)
+			if err != nil {
+				return err
+			}
+			// The user must still be active and have the email the link was sent to.
+			res := tx.Model(&models.User{}).
+				Where("id = ? AND email = ? AND status = ?", emailToken.UserID, emailToken.Email, models.UserActive).
+				Update("password_hash", string(hashedPassword))
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return errInvalidEmailToken
+			}
+			return revokeSessions(tx, models.SessionPasswordChanged, "user_id = ?", emailToken.UserID)
+		})
+		if errors.Is(err, errInvalidEmailToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The link is invalid or has expired"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+	}
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"backend/models"
+)
+
+// errPermissionStatusChanged is returned when a permission request left the
+// status a change was based on before the change was saved.
+var errPermissionStatusChanged = errors.New("permission request status changed")
+
+// recordPermissionChange appends the current status of a request to its history.
+func recordPermissionChange(tx *gorm.DB, req *models.PermissionRequest, action, from string, companyID uint, email, comment string) error {
+	return tx.Create(&models.PermissionRequestHistory{
+		PermissionRequestID: req.ID,
+		Action:              action,
+		FromStatus:          from,
+		ToStatus:            req.Status,
+		ExpiresAt:           req.ExpiresAt,
+		ActorCompanyID:      companyID,
+		ActorEmail:          email,
+		Comment:             comment,
+	}).Error
+}
+
+// updatePermissionStatus moves a request to a new status together with the
+// given fields and records the change. The update only applies while the
+// request still has the status it was loaded with.
+func updatePermissionStatus(tx *gorm.DB, req *models.PermissionRequest, action, to string, fields map[string]interface{}, companyID uint, email, comment string) error {
+	fields["status"] = to
+	res := tx.Model(&models.PermissionRequest{}).
+		Where("id = ? AND status = ?", req.ID, req.Status).
+		Updates(fields)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errPermissionStatusChanged
+	}
+
+	from := req.Status
+	if err := tx.First(req, req.ID).Error; err != nil {
+		return err
+	}
+	return recordPermissionChange(tx, req, action, from, companyID, email, comment)
+}
+
+// loadPermissionRequest loads the request named by the requestId parameter
+// along with the authenticated company id. It writes the error response and
+// returns nil when the request cannot be loaded.
+func loadPermissionRequest(c *gin.Context, db *gorm.DB) (*models.PermissionRequest, uint) {
+	companyIDVal, exists := c.Get("companyID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, 0
+	}
+	companyID, ok := companyIDVal.(uint)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+		return nil, 0
+	}
+
+	reqID, err := strconv.Atoi(c.Param("requestId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request id"})
+		return nil, 0
+	}
+	var req models.PermissionRequest
+	if err := db.First(&req, reqID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Request not found"})
+		return nil, 0
+	}
+	return &req, companyID
+}
+
+// permissionAction loads the request named by the requestId parameter and
+// checks that the authenticated company is its seller (or its requester).
+// It writes the error response and returns nil when it is not.
+func permissionAction(c *gin.Context, db *gorm.DB, asSeller bool) (*models.PermissionRequest, uint) {
+	req, companyID := loadPermissionRequest(c, db)
+	if req == nil {
+		return nil, 0
+	}
+	if (asSeller && req.SellerID != companyID) || (!asSeller && req.RequesterID != companyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed"})
+		return nil, 0
+	}
+	return req, companyID
+}
+
+// permissionParticipant is like permissionAction but accepts both the seller and the requester.
+func permissionParticipant(c *gin.Context, db *gorm.DB) (*models.PermissionRequest, uint) {
+	req, companyID := loadPermissionRequest(c, db)
+	if req == nil {
+		return nil, 0
+	}
+	if req.SellerID != companyID && req.RequesterID != companyID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return nil, 0
+	}
+	return req, companyID
+}
+
+// respondPermissionChange writes the outcome of updatePermissionStatus.
+func respondPermissionChange(c *gin.Context, db *gorm.DB, req *models.PermissionRequest, err error) {
+	if errors.Is(err, errPermissionStatusChanged) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The request was changed concurrently, please reload"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update request"})
+		return
+	}
+	if err := preloadMessages(db).Preload("Scopes").First(req, req.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch request"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Request updated", "request": req})
+}
+
+// RevokePermissionRequestHandler lets the seller end a permitted grant. The
+// request keeps its history and scopes; the buyer loses access immediately.
+// Expected JSON body: { "reason": "Contract terminated" }
+func RevokePermissionRequestHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, sellerID := permissionAction(c, db, true)
+		if req == nil {
+			return
+		}
+
+		var body struct {
+			Reason string `json:"reason"`
+		}
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		reason, ok := validPermissionMessage(body.Reason)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A reason of at most 2000 characters is required"})
+			return
+		}
+		body.Reason = reason
+		if req.Status != models.PermissionPermitted {
+			c.JSON(http.StatusConflict, gin.H{"error": "Only permitted requests can be revoked"})
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			fields := map[string]interface{}{"revoked_at": time.Now(), "revocation_reason": body.Reason}
+			if err := updatePermissionStatus(tx, req, "revoke", models.PermissionRevoked, fields, sellerID, c.GetString("email"), body.Reason); err != nil {
+				return err
+			}
+			_, err := addPermissionMessage(tx, req.ID, sellerID, c.GetString("email"), body.Reason)
+			return err
+		})
+		respondPermissionChange(c, db, req, err)
+	}
+}
+
+// WithdrawPermissionRequestHandler lets the buyer withdraw a request the seller has not decided yet.
+func WithdrawPermissionRequestHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, buyerID := permissionAction(c, db, false)
+		if req == nil {
+			return
+		}
+		if req.Status != models.PermissionPending {
+			c.JSON(http.StatusConflict, gin.H{"error": "Only pending requests can be withdrawn"})
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return updatePermissionStatus(tx, req, "withdraw", models.PermissionWithdrawn, map[string]interface{}{}, buyerID, c.GetString("email"), "")
+		})
+		respondPermissionChange(c, db, req, err)
+	}
+}
+
+// RenewPermissionRequestHandler lets the seller extend a permitted grant or
+// reinstate an expired one, keeping its scopes and price lists. Without
+// expires_at the grant no longer expires.
+// Expected JSON body: { "expires_at": "2027-03-31T23:59:59Z" }
+func RenewPermissionRequestHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, sellerID := permissionAction(c, db, true)
+		if req == nil {
+			return
+		}
+
+		var body struct {
+			ExpiresAt *time.Time `json:"expires_at"`
+			Comment   string     `json:"comment"`
+		}
+		if err := c.BindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		if body.ExpiresAt != nil && !body.ExpiresAt.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+			return
+		}
+		if req.Status != models.PermissionPermitted && req.Status != models.PermissionExpired {
+			c.JSON(http.StatusConflict, gin.H{"error": "Only permitted or expired grants can be renewed"})
+			return
+		}
+
+		// An expired grant must not be reinstated while the buyer holds a newer one.
+		if req.Status == models.PermissionExpired {
+			var count int64
+			if err := db.Model(&models.PermissionRequest{}).
+				Where("seller_id = ? AND requester_id = ? AND id <> ? AND status IN ?", req.SellerID, req.RequesterID, req.ID,
+					[]string{models.PermissionPending, models.PermissionPermitted}).
+				Count(&count).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing requests"})
+				return
+			}
+			if count > 0 {
+				c.JSON(http.StatusConflict, gin.H{"error": "The buyer has a newer request for this seller"})
+				return
+			}
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			fields := map[string]interface{}{"expires_at": body.ExpiresAt}
+			return updatePermissionStatus(tx, req, "renew", models.PermissionPermitted, fields, sellerID, c.GetString("email"), body.Comment)
+		})
+		respondPermissionChange(c, db, req, err)
+	}
+}
+
+// GetPermissionRequestHistoryHandler returns the status changes of a request, oldest first,
+// to its seller or requester.
+func GetPermissionRequestHistoryHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, _ := permissionParticipant(c, db)
+		if req == nil {
+			return
+		}
+
+		history := []models.PermissionRequestHistory{}
+		if err := db.Where("permission_request_id = ?", req.ID).Order("created_at, id").Find(&history).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch request history"})
+			return
+		}
+		c.JSON(http.StatusOK, history)
+	}
+}
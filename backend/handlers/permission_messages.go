@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"backend/models"
+)
+
+// maxPermissionMessageLength is the longest message body accepted, in characters.
+const maxPermissionMessageLength = 2000
+
+// validPermissionMessage trims a message body and reports whether it may be posted.
+func validPermissionMessage(body string) (string, bool) {
+	body = strings.TrimSpace(body)
+	return body, body != "" && utf8.RuneCountInString(body) <= maxPermissionMessageLength
+}
+
+// addPermissionMessage appends a message to the conversation of a request.
+func addPermissionMessage(tx *gorm.DB, requestID, companyID uint, email, body string) (models.PermissionMessage, error) {
+	msg := models.PermissionMessage{
+		PermissionRequestID: requestID,
+		AuthorCompanyID:     companyID,
+		AuthorEmail:         email,
+		Body:                body,
+	}
+	err := tx.Create(&msg).Error
+	return msg, err
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern so that s matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// preloadMessages loads the conversation of permission requests, oldest message first.
+func preloadMessages(db *gorm.DB) *gorm.DB {
+	return db.Preload("Messages", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("created_at, id")
+	})
+}
+
+// GetPermissionMessagesHandler returns the conversation of a request to its seller or requester.
+func GetPermissionMessagesHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, _ := permissionParticipant(c, db)
+		if req == nil {
+			return
+		}
+
+		messages := []models.PermissionMessage{}
+		if err := db.Where("permission_request_id = ?", req.ID).Order("created_at, id").Find(&messages).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
+			return
+		}
+		c.JSON(http.StatusOK, messages)
+	}
+}
+
+// PostPermissionMessageHandler adds a message to the conversation of a request.
+// Both the seller and the requester may post, whatever the request status.
+// Expected JSON body: { "body": "We are a retailer in Osaka ..." }
+func PostPermissionMessageHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, companyID := permissionParticipant(c, db)
+		if req == nil {
+			return
+		}
+
+		var reqBody struct {
+			Body string `json:"body"`
+		}
+		if err := c.BindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		body, ok := validPermissionMessage(reqBody.Body)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Message must be between 1 and 2000 characters"})
+			return
+		}
+
+		msg, err := addPermissionMessage(db, req.ID, companyID, c.GetString("email"), body)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to post message"})
+			return
+		}
+		c.JSON(http.StatusCreated, msg)
+	}
+}
@@ -1,8 +1,12 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -10,8 +14,10 @@ import (
 	"backend/models"
 )
 
-// SendPermissionRequestHandler now expects a seller_email in the JSON body.
+// SendPermissionRequestHandler now expects a seller_email in the JSON body, and optionally a
+// message introducing the buyer, which starts the request's conversation.
 // The customer's email and phone are fetched from the Company record using the auth companyID.
+// Expected JSON body: { "seller_email": "sales@example.com", "message": "We are a retailer in Osaka ..." }
 func SendPermissionRequestHandler(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get the requester (customer) company id from Auth middleware.
@@ -29,6 +35,7 @@ func SendPermissionRequestHandler(db *gorm.DB) gin.HandlerFunc {
 		// Parse the request body to get the seller's email.
 		var reqBody struct {
 			SellerEmail string `json:"seller_email"`
+			Message     string `json:"message"`
 		}
 		if err := c.BindJSON(&reqBody); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
@@ -38,6 +45,11 @@ func SendPermissionRequestHandler(db *gorm.DB) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Seller email is required"})
 			return
 		}
+		message, ok := validPermissionMessage(reqBody.Message)
+		if !ok && message != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Message must be at most 2000 characters"})
+			return
+		}
 
 		// Look up the seller by email.
 		var seller models.Companies
@@ -59,15 +71,26 @@ func SendPermissionRequestHandler(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		// Check for duplicate request.
-		var existingCount int64
-		db.Model(&models.PermissionRequest{}).
-			Where("seller_id = ? AND requester_id = ? AND status IN ?", seller.ID, requesterID, []string{"pending", "permitted"}).
-			Count(&existingCount)
-		if existingCount > 0 {
-			c.JSON(http.StatusConflict, gin.H{"error": "A permission request already exists for this seller"})
+		// Check for duplicate request. A new request is allowed once the previous one
+		// was rejected, withdrawn, revoked or has expired; an expired grant the
+		// seller wants to keep can also be renewed by the seller instead.
+		var existing []models.PermissionRequest
+		if err := db.Where("seller_id = ? AND requester_id = ? AND status IN ?", seller.ID, requesterID,
+			[]string{models.PermissionPending, models.PermissionPermitted}).Find(&existing).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing requests"})
 			return
 		}
+		now := time.Now()
+		for _, r := range existing {
+			if r.Status == models.PermissionPending {
+				c.JSON(http.StatusConflict, gin.H{"error": "A permission request is already pending for this seller"})
+				return
+			}
+			if r.Active(now) {
+				c.JSON(http.StatusConflict, gin.H{"error": "You already have access to this seller"})
+				return
+			}
+		}
 
 		// Create the permission request.
 		permissionReq := models.PermissionRequest{
@@ -75,9 +98,23 @@ func SendPermissionRequestHandler(db *gorm.DB) gin.HandlerFunc {
 			RequesterID:    requester.ID,
 			RequesterEmail: requester.Email,
 			RequesterPhone: requester.Phone,
-			Status:         "pending",
+			Status:         models.PermissionPending,
 		}
-		if err := db.Create(&permissionReq).Error; err != nil {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&permissionReq).Error; err != nil {
+				return err
+			}
+			if err := recordPermissionChange(tx, &permissionReq, "create", "", requesterID, c.GetString("email"), ""); err != nil {
+				return err
+			}
+			if message == "" {
+				return nil
+			}
+			msg, err := addPermissionMessage(tx, permissionReq.ID, requesterID, c.GetString("email"), message)
+			permissionReq.Messages = append(permissionReq.Messages, msg)
+			return err
+		})
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create permission request"})
 			return
 		}
@@ -85,7 +122,8 @@ func SendPermissionRequestHandler(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
-// GetPermissionRequestsHandler returns all permission requests for the seller (current company).
+// GetPermissionRequestsHandler returns all permission requests for the seller (current company),
+// with the requester's company name.
 func GetPermissionRequestsHandler(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Current seller id from auth.
@@ -101,54 +139,222 @@ func GetPermissionRequestsHandler(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		var requests []models.PermissionRequest
-		if err := db.Where("seller_id = ?", sellerID).Find(&requests).Error; err != nil {
+		if err := preloadMessages(db).Preload("Scopes").Where("seller_id = ?", sellerID).Order("created_at DESC, id DESC").Find(&requests).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch requests"})
 			return
 		}
-
-		// Optionally, you could join with the Company table to get the requester company name.
-		c.JSON(http.StatusOK, requests)
+		response, err := withCompanyNames(db, requests)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch requests"})
+			return
+		}
+		c.JSON(http.StatusOK, response)
 	}
 }
 
-// SearchPermissionRequestsHandler lets the seller search requests by phone and email.
-// Query parameters: email, phone
+// SearchPermissionRequestsHandler lets the seller search incoming requests.
+// See searchPermissionRequests for the query parameters.
 func SearchPermissionRequestsHandler(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		sellerVal, exists := c.Get("companyID")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-			return
+		searchPermissionRequests(c, db, true)
+	}
+}
+
+// GetOutgoingPermissionRequestsHandler lets the buyer list and search the requests it has sent,
+// with the seller's company name. See searchPermissionRequests for the query parameters.
+func GetOutgoingPermissionRequestsHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		searchPermissionRequests(c, db, false)
+	}
+}
+
+// permissionRequestResponse is a permission request with the names of both companies.
+type permissionRequestResponse struct {
+	models.PermissionRequest
+	SellerName    string `json:"seller_name"`
+	RequesterName string `json:"requester_name"`
+}
+
+// withCompanyNames attaches the seller and requester company names to requests.
+func withCompanyNames(db *gorm.DB, requests []models.PermissionRequest) ([]permissionRequestResponse, error) {
+	ids := []uint{}
+	for _, r := range requests {
+		ids = append(ids, r.SellerID, r.RequesterID)
+	}
+	var companies []models.Companies
+	if len(ids) > 0 {
+		if err := db.Unscoped().Select("id, name").Where("id IN ?", uniqueIDs(ids)).Find(&companies).Error; err != nil {
+			return nil, err
 		}
-		sellerID, ok := sellerVal.(uint)
-		if !ok {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid seller ID"})
-			return
+	}
+	names := map[uint]string{}
+	for _, company := range companies {
+		names[company.ID] = company.Name
+	}
+
+	response := make([]permissionRequestResponse, len(requests))
+	for i, r := range requests {
+		response[i] = permissionRequestResponse{
+			PermissionRequest: r,
+			SellerName:        names[r.SellerID],
+			RequesterName:     names[r.RequesterID],
 		}
+	}
+	return response, nil
+}
 
-		email := c.Query("email")
-		phone := c.Query("phone")
+// Page sizes of the permission request search.
+const (
+	defaultPermissionPageSize = 50
+	maxPermissionPageSize     = 200
+)
 
-		var requests []models.PermissionRequest
-		query := db.Where("seller_id = ?", sellerID)
-		if email != "" {
-			query = query.Where("requester_email = ?", email)
+// permissionRequestSorts maps the accepted sort parameters to columns.
+// "company" sorts by the name of the other party.
+var permissionRequestSorts = map[string]string{
+	"created_at": "permission_requests.created_at",
+	"updated_at": "permission_requests.updated_at",
+	"expires_at": "permission_requests.expires_at",
+	"status":     "permission_requests.status",
+	"company":    "(SELECT name FROM companies WHERE companies.id = permission_requests.%s)",
+}
+
+// searchPermissionRequests writes the requests received by the authenticated
+// company (asSeller) or sent by it, filtered, sorted and paginated. Text
+// filters match partially and ignore case; email, phone and company match the
+// other party, i.e. the requester for a seller and the seller for a buyer.
+// The total number of matches is returned in the X-Total-Count header.
+// Query parameters: status (comma separated), email, phone, company, message,
+// from, to (created date), sort (created_at, updated_at, expires_at, status, company),
+// order (asc, desc), page, page_size
+func searchPermissionRequests(c *gin.Context, db *gorm.DB, asSeller bool) {
+	companyIDVal, exists := c.Get("companyID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	companyID, ok := companyIDVal.(uint)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+		return
+	}
+
+	query := db.Model(&models.PermissionRequest{})
+	other := "seller_id"
+	if asSeller {
+		query = query.Where("permission_requests.seller_id = ?", companyID)
+		other = "requester_id"
+	} else {
+		query = query.Where("permission_requests.requester_id = ?", companyID)
+	}
+	// Text filters on the other party's company.
+	companyFilter := func(column, value string) {
+		query = query.Where("permission_requests."+other+" IN (SELECT id FROM companies WHERE "+column+" ILIKE ?)", "%"+escapeLike(value)+"%")
+	}
+
+	if status := c.Query("status"); status != "" {
+		query = query.Where("permission_requests.status IN ?", strings.Split(status, ","))
+	}
+	if email := strings.TrimSpace(c.Query("email")); email != "" {
+		if asSeller {
+			query = query.Where("permission_requests.requester_email ILIKE ?", "%"+escapeLike(email)+"%")
+		} else {
+			companyFilter("email", email)
 		}
-		if phone != "" {
-			query = query.Where("requester_phone = ?", phone)
+	}
+	if phone := strings.TrimSpace(c.Query("phone")); phone != "" {
+		if asSeller {
+			query = query.Where("permission_requests.requester_phone ILIKE ?", "%"+escapeLike(phone)+"%")
+		} else {
+			companyFilter("phone", phone)
 		}
-
-		if err := query.Find(&requests).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+	}
+	if company := strings.TrimSpace(c.Query("company")); company != "" {
+		companyFilter("name", company)
+	}
+	if message := strings.TrimSpace(c.Query("message")); message != "" {
+		query = query.Where("permission_requests.id IN (SELECT permission_request_id FROM permission_messages WHERE body ILIKE ?)", "%"+escapeLike(message)+"%")
+	}
+	if fromParam := c.Query("from"); fromParam != "" {
+		from, _, err := parseTimeParam(fromParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date"})
+			return
+		}
+		query = query.Where("permission_requests.created_at >= ?", from)
+	}
+	if toParam := c.Query("to"); toParam != "" {
+		to, dateOnly, err := parseTimeParam(toParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date"})
 			return
 		}
-		c.JSON(http.StatusOK, requests)
+		// A plain date includes the whole day.
+		if dateOnly {
+			query = query.Where("permission_requests.created_at < ?", to.AddDate(0, 0, 1))
+		} else {
+			query = query.Where("permission_requests.created_at <= ?", to)
+		}
+	}
+
+	sort := c.DefaultQuery("sort", "created_at")
+	column, ok := permissionRequestSorts[sort]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort"})
+		return
+	}
+	if sort == "company" {
+		column = fmt.Sprintf(column, other)
+	}
+	order := strings.ToLower(c.DefaultQuery("order", "desc"))
+	if order != "asc" && order != "desc" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Order must be 'asc' or 'desc'"})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page"})
+		return
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultPermissionPageSize)))
+	if err != nil || pageSize < 1 || pageSize > maxPermissionPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("page_size must be between 1 and %d", maxPermissionPageSize)})
+		return
 	}
+
+	// The filtered query is used for both the count and the page.
+	query = query.Session(&gorm.Session{})
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+		return
+	}
+
+	var requests []models.PermissionRequest
+	if err := preloadMessages(query).Preload("Scopes").
+		Order(column + " " + order + " NULLS LAST, permission_requests.id " + order).
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&requests).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+		return
+	}
+	response, err := withCompanyNames(db, requests)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+		return
+	}
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	c.JSON(http.StatusOK, response)
 }
 
-// UpdatePermissionRequestHandler allows the seller to update a request status to "permitted" (or "rejected").
+// UpdatePermissionRequestHandler allows the seller to decide a pending request ("permitted" or "rejected"),
+// optionally with an expiry date for the grant, and to limit what a permitted buyer sees. A given scope
+// replaces the current one; empty lists share the whole catalog. Status and scope may each be omitted.
+// Active grants are ended with the revoke action, not by rejecting them.
 // URL parameter: requestId
-// Expected JSON body: { "status": "permitted" }
+// Expected JSON body:
+// { "status": "permitted", "expires_at": "2026-03-31T23:59:59Z", "scope": { "product_ids": [1], "categories": ["Tools"], "warehouse_ids": [2] } }
 func UpdatePermissionRequestHandler(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get seller id from auth.
@@ -171,16 +377,33 @@ func UpdatePermissionRequestHandler(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		var reqBody struct {
-			Status string `json:"status"`
+			Status    string                `json:"status"`
+			ExpiresAt *time.Time            `json:"expires_at"`
+			Comment   string                `json:"comment"`
+			Scope     *permissionScopeInput `json:"scope"`
 		}
 		if err := c.BindJSON(&reqBody); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 			return
 		}
-		if reqBody.Status != "permitted" && reqBody.Status != "rejected" {
+		if reqBody.Status == "" && reqBody.Scope == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Status or scope is required"})
+			return
+		}
+		if reqBody.Status != "" && reqBody.Status != models.PermissionPermitted && reqBody.Status != models.PermissionRejected {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Status must be 'permitted' or 'rejected'"})
 			return
 		}
+		comment, ok := validPermissionMessage(reqBody.Comment)
+		if !ok && comment != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Comment must be at most 2000 characters"})
+			return
+		}
+		reqBody.Comment = comment
+		if reqBody.ExpiresAt != nil && (reqBody.Status != models.PermissionPermitted || !reqBody.ExpiresAt.After(time.Now())) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be a future date given when permitting"})
+			return
+		}
 
 		// Ensure the request belongs to this seller.
 		var permissionReq models.PermissionRequest
@@ -192,11 +415,68 @@ func UpdatePermissionRequestHandler(db *gorm.DB) gin.HandlerFunc {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed"})
 			return
 		}
+		if reqBody.Status != "" && permissionReq.Status != models.PermissionPending {
+			c.JSON(http.StatusConflict, gin.H{"error": "Only pending requests can be permitted or rejected"})
+			return
+		}
+
+		var scopes []models.PermissionScope
+		if reqBody.Scope != nil {
+			var msg string
+			scopes, msg, err = reqBody.Scope.scopes(db, sellerID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update request"})
+				return
+			}
+			if msg != "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+				return
+			}
+		}
 
-		if err := db.Model(&permissionReq).Update("status", reqBody.Status).Error; err != nil {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if reqBody.Status != "" {
+				action := "permit"
+				if reqBody.Status == models.PermissionRejected {
+					action = "reject"
+				}
+				fields := map[string]interface{}{"expires_at": reqBody.ExpiresAt}
+				if err := updatePermissionStatus(tx, &permissionReq, action, reqBody.Status, fields, sellerID, c.GetString("email"), reqBody.Comment); err != nil {
+					return err
+				}
+				// The comment, e.g. the reason for a rejection, is shown to the buyer.
+				if reqBody.Comment != "" {
+					if _, err := addPermissionMessage(tx, permissionReq.ID, sellerID, c.GetString("email"), reqBody.Comment); err != nil {
+						return err
+					}
+				}
+			}
+			if reqBody.Scope == nil {
+				return nil
+			}
+			if err := tx.Where("permission_request_id = ?", permissionReq.ID).Delete(&models.PermissionScope{}).Error; err != nil {
+				return err
+			}
+			if len(scopes) == 0 {
+				return nil
+			}
+			for i := range scopes {
+				scopes[i].PermissionRequestID = permissionReq.ID
+			}
+			return tx.Create(&scopes).Error
+		})
+		if errors.Is(err, errPermissionStatusChanged) {
+			c.JSON(http.StatusConflict, gin.H{"error": "The request was changed concurrently, please reload"})
+			return
+		}
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update request"})
 			return
 		}
+		if err := preloadMessages(db).Preload("Scopes").First(&permissionReq, permissionReq.ID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch request"})
+			return
+		}
 		c.JSON(http.StatusOK, gin.H{"message": "Request updated", "request": permissionReq})
 	}
 }
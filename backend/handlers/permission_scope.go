@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"backend/models"
+)
+
+// activeGrantSQL restricts a query joining permission_requests to the requests
+// that currently grant access, see models.PermissionRequest.Active.
+const activeGrantSQL = `permission_requests.deleted_at IS NULL AND permission_requests.status = '` + models.PermissionPermitted + `'
+    AND (permission_requests.expires_at IS NULL OR permission_requests.expires_at > NOW())`
+
+// sharedProductSQL restricts a query joining products and permission_requests
+// to the products the request shares: all of them when the request has no
+// scopes, otherwise those matching a product, category or warehouse scope.
+const sharedProductSQL = `(
+    NOT EXISTS (SELECT 1 FROM permission_scopes s WHERE s.permission_request_id = permission_requests.id)
+    OR EXISTS (
+        SELECT 1 FROM permission_scopes s
+        WHERE s.permission_request_id = permission_requests.id
+          AND (s.product_id = products.id
+               OR (s.category <> '' AND s.category = products.category)
+               OR s.warehouse_id IN (
+                   SELECT st.warehouse_id FROM inventory_stocks st
+                   WHERE st.product_id = products.id AND st.deleted_at IS NULL))))`
+
+// productShared reports whether an active grant of the buyer shares the product.
+func productShared(db *gorm.DB, buyerID, productID uint) (bool, error) {
+	var count int64
+	err := db.Table("products").
+		Joins("JOIN permission_requests ON permission_requests.seller_id = products.supplier_id AND permission_requests.requester_id = ? AND "+activeGrantSQL, buyerID).
+		Where("products.id = ?", productID).
+		Where(sharedProductSQL).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// sharedWarehouses returns, per seller, the warehouses the buyer's active
+// grants limit stock to: the warehouse scopes, when every grant of the seller
+// has some. Sellers missing from the result share all their warehouses.
+func sharedWarehouses(db *gorm.DB, buyerID uint, sellerIDs []uint) (map[uint]map[uint]bool, error) {
+	result := map[uint]map[uint]bool{}
+	if len(sellerIDs) == 0 {
+		return result, nil
+	}
+	var rows []struct {
+		SellerID    uint
+		WarehouseID *uint
+	}
+	if err := db.Table("permission_requests").
+		Select("permission_requests.seller_id, s.warehouse_id").
+		Joins("LEFT JOIN permission_scopes s ON s.permission_request_id = permission_requests.id AND s.warehouse_id IS NOT NULL").
+		Where("permission_requests.requester_id = ? AND permission_requests.seller_id IN ? AND "+activeGrantSQL, buyerID, sellerIDs).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	unrestricted := map[uint]bool{}
+	for _, row := range rows {
+		if row.WarehouseID == nil {
+			unrestricted[row.SellerID] = true
+			continue
+		}
+		if result[row.SellerID] == nil {
+			result[row.SellerID] = map[uint]bool{}
+		}
+		result[row.SellerID][*row.WarehouseID] = true
+	}
+	for sellerID := range unrestricted {
+		delete(result, sellerID)
+	}
+	return result, nil
+}
+
+// permissionScopeInput lists what a seller shares with a buyer. All lists
+// empty means the whole catalog.
+type permissionScopeInput struct {
+	ProductIDs   []uint   `json:"product_ids"`
+	Categories   []string `json:"categories"`
+	WarehouseIDs []uint   `json:"warehouse_ids"`
+}
+
+// scopes validates the input against the seller's products and warehouses and
+// returns the scope rows. It returns an error message for the client, or "" on success.
+func (in *permissionScopeInput) scopes(db *gorm.DB, sellerID uint) ([]models.PermissionScope, string, error) {
+	scopes := []models.PermissionScope{}
+
+	for _, id := range uniqueIDs(in.ProductIDs) {
+		var count int64
+		if err := db.Model(&models.Products{}).Where("id = ? AND supplier_id = ?", id, sellerID).Count(&count).Error; err != nil {
+			return nil, "", err
+		}
+		if count == 0 {
+			return nil, fmt.Sprintf("Product %d not found", id), nil
+		}
+		productID := id
+		scopes = append(scopes, models.PermissionScope{ProductID: &productID})
+	}
+
+	seen := map[string]bool{}
+	for _, category := range in.Categories {
+		category = strings.TrimSpace(category)
+		if category == "" || len(category) > 50 {
+			return nil, "Categories must be between 1 and 50 characters", nil
+		}
+		if seen[category] {
+			continue
+		}
+		seen[category] = true
+		scopes = append(scopes, models.PermissionScope{Category: category})
+	}
+
+	for _, id := range uniqueIDs(in.WarehouseIDs) {
+		var count int64
+		if err := db.Model(&models.Warehouse{}).Where("id = ? AND company_id = ?", id, sellerID).Count(&count).Error; err != nil {
+			return nil, "", err
+		}
+		if count == 0 {
+			return nil, fmt.Sprintf("Warehouse %d not found", id), nil
+		}
+		warehouseID := id
+		scopes = append(scopes, models.PermissionScope{WarehouseID: &warehouseID})
+	}
+	return scopes, "", nil
+}
+
+// uniqueIDs returns ids without duplicates, keeping their order.
+func uniqueIDs(ids []uint) []uint {
+	seen := map[uint]bool{}
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"backend/models"
+)
+
+// priceListInput is the payload for creating or replacing a price list.
+type priceListInput struct {
+	PermissionRequestID uint       `json:"permission_request_id"` // create only
+	Name                string     `json:"name"`
+	Percent             uint       `json:"percent"`
+	EffectiveFrom       *time.Time `json:"effective_from"`
+	EffectiveTo         *time.Time `json:"effective_to"`
+	Items               []struct {
+		ProductID uint         `json:"product_id"`
+		UnitPrice models.Money `json:"unit_price"`
+	} `json:"items"`
+}
+
+// apply validates the input against the seller's products and copies it onto
+// list. It returns an error message for the client, or "" on success.
+func (in *priceListInput) apply(db *gorm.DB, list *models.PriceList) (string, error) {
+	name := strings.TrimSpace(in.Name)
+	if name == "" || len(name) > 100 {
+		return "Name is required and must be at most 100 characters", nil
+	}
+	if in.Percent > 100 {
+		return "Percent must be between 0 and 100", nil
+	}
+	if in.EffectiveFrom != nil && in.EffectiveTo != nil && !in.EffectiveTo.After(*in.EffectiveFrom) {
+		return "effective_to must be after effective_from", nil
+	}
+
+	items := make([]models.PriceListItem, 0, len(in.Items))
+	seen := map[uint]bool{}
+	for _, item := range in.Items {
+		if seen[item.ProductID] {
+			return fmt.Sprintf("Product %d is listed twice", item.ProductID), nil
+		}
+		seen[item.ProductID] = true
+
+		var product models.Products
+		if err := db.Select("id", "supplier_id", "currency").First(&product, item.ProductID).Error; err != nil || product.SupplierID != list.SellerID {
+			return fmt.Sprintf("Product %d not found", item.ProductID), nil
+		}
+		if item.UnitPrice <= 0 || item.UnitPrice.Truncate(product.Currency) != item.UnitPrice {
+			return fmt.Sprintf("Unit price of product %d must be a positive amount in %s", product.ID, product.Currency), nil
+		}
+		items = append(items, models.PriceListItem{ProductID: product.ID, UnitPrice: item.UnitPrice})
+	}
+
+	// Only one list may apply to a buyer at any time.
+	overlap := db.Model(&models.PriceList{}).
+		Where("seller_id = ? AND buyer_id = ? AND id <> ?", list.SellerID, list.BuyerID, list.ID)
+	if in.EffectiveTo != nil {
+		overlap = overlap.Where("effective_from IS NULL OR effective_from < ?", *in.EffectiveTo)
+	}
+	if in.EffectiveFrom != nil {
+		overlap = overlap.Where("effective_to IS NULL OR effective_to > ?", *in.EffectiveFrom)
+	}
+	var count int64
+	if err := overlap.Count(&count).Error; err != nil {
+		return "", err
+	}
+	if count > 0 {
+		return "The effective dates overlap another price list of this buyer", nil
+	}
+
+	list.Name = name
+	list.Percent = in.Percent
+	list.EffectiveFrom = in.EffectiveFrom
+	list.EffectiveTo = in.EffectiveTo
+	list.Items = items
+	return "", nil
+}
+
+// GetPriceListsHandler lists the price lists the authenticated company issued
+// as seller or received as buyer, newest first.
+func GetPriceListsHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated company id.
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		lists := []models.PriceList{}
+		if err := db.Preload("Items").
+			Where("seller_id = ? OR buyer_id = ?", companyID, companyID).
+			Order("created_at DESC").
+			Find(&lists).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch price lists"})
+			return
+		}
+		c.JSON(http.StatusOK, lists)
+	}
+}
+
+// GetPriceListHandler returns one price list to its seller or buyer.
+func GetPriceListHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		listID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid price list id"})
+			return
+		}
+
+		// Get authenticated company id.
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		var list models.PriceList
+		if err := db.Preload("Items").First(&list, listID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Price list not found"})
+			return
+		}
+		if list.SellerID != companyID && list.BuyerID != companyID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+// CreatePriceListHandler attaches a price list to one of the seller's permitted buyers.
+// Unit prices are in each product's currency.
+// Expected JSON body:
+// { "permission_request_id": 3, "name": "2025 contract", "percent": 5, "effective_from": "2025-04-01T00:00:00Z", "items": [{ "product_id": 7, "unit_price": "850" }] }
+func CreatePriceListHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get authenticated company id.
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		var req priceListInput
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+
+		var permissionReq models.PermissionRequest
+		if err := db.First(&permissionReq, req.PermissionRequestID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Permission request not found"})
+			return
+		}
+		if permissionReq.SellerID != companyID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed"})
+			return
+		}
+		if !permissionReq.Active(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Price lists can only be attached to permitted buyers"})
+			return
+		}
+
+		list := models.PriceList{
+			SellerID:            companyID,
+			BuyerID:             permissionReq.RequesterID,
+			PermissionRequestID: permissionReq.ID,
+		}
+		msg, err := req.apply(db, &list)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create price list"})
+			return
+		}
+		if msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+
+		if err := db.Create(&list).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create price list"})
+			return
+		}
+		c.JSON(http.StatusCreated, list)
+	}
+}
+
+// UpdatePriceListHandler replaces the name, dates, percentage and items of a price list.
+// Orders already placed keep the prices they were charged.
+func UpdatePriceListHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		listID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid price list id"})
+			return
+		}
+
+		// Get authenticated company id.
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		var req priceListInput
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+
+		var list models.PriceList
+		if err := db.First(&list, listID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Price list not found"})
+			return
+		}
+		if list.SellerID != companyID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can only update your own price lists"})
+			return
+		}
+
+		msg, err := req.apply(db, &list)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update price list"})
+			return
+		}
+		if msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Omit("Items").Save(&list).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("price_list_id = ?", list.ID).Delete(&models.PriceListItem{}).Error; err != nil {
+				return err
+			}
+			if len(list.Items) == 0 {
+				return nil
+			}
+			for i := range list.Items {
+				list.Items[i].PriceListID = list.ID
+			}
+			return tx.Create(&list.Items).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update price list"})
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+// DeletePriceListHandler deletes one of the seller's price lists; the buyer falls back to list prices.
+func DeletePriceListHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		listID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid price list id"})
+			return
+		}
+
+		// Get authenticated company id.
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		res := db.Where("id = ? AND seller_id = ?", listID, companyID).Delete(&models.PriceList{})
+		if res.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete price list"})
+			return
+		}
+		if res.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Price list not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Price list deleted"})
+	}
+}
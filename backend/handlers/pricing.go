@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"backend/models"
+)
+
+// errCouponExhausted is returned when a coupon reached its usage limit while an order was placed.
+var errCouponExhausted = errors.New("coupon usage limit reached")
+
+// couponError is a coupon that cannot be applied to a cart.
+type couponError struct {
+	Message string
+}
+
+func (e *couponError) Error() string {
+	return e.Message
+}
+
+// normalizeCouponCode returns the canonical, upper-case form of a coupon code.
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// mulDiv returns m * num / den truncated towards zero, without overflowing int64.
+func mulDiv(m models.Money, num, den int64) models.Money {
+	v := new(big.Int).Mul(big.NewInt(int64(m)), big.NewInt(num))
+	return models.Money(v.Quo(v, big.NewInt(den)).Int64())
+}
+
+// couponUsable reports why a coupon cannot be redeemed at the given time, or "" if it can.
+func couponUsable(coupon *models.Coupon, at time.Time) string {
+	switch {
+	case !coupon.Active:
+		return fmt.Sprintf("Coupon %s is no longer active", coupon.Code)
+	case coupon.ValidFrom != nil && at.Before(*coupon.ValidFrom):
+		return fmt.Sprintf("Coupon %s is not valid yet", coupon.Code)
+	case coupon.ValidUntil != nil && at.After(*coupon.ValidUntil):
+		return fmt.Sprintf("Coupon %s has expired", coupon.Code)
+	case coupon.MaxUses > 0 && coupon.UsedCount >= coupon.MaxUses:
+		return fmt.Sprintf("Coupon %s has been used up", coupon.Code)
+	}
+	return ""
+}
+
+// redeemCoupon counts one use of a coupon. The usage limit is checked in the
+// update itself so concurrent checkouts cannot exceed it.
+func redeemCoupon(tx *gorm.DB, couponID uint) error {
+	res := tx.Model(&models.Coupon{}).
+		Where("id = ? AND (max_uses = 0 OR used_count < max_uses)", couponID).
+		Update("used_count", gorm.Expr("used_count + 1"))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errCouponExhausted
+	}
+	return nil
+}
+
+// loadPriceBreaks returns the price breaks of the given products keyed by
+// product id, highest minimum quantity first.
+func loadPriceBreaks(db *gorm.DB, productIDs []uint) (map[uint][]models.PriceBreak, error) {
+	result := map[uint][]models.PriceBreak{}
+	if len(productIDs) == 0 {
+		return result, nil
+	}
+	var breaks []models.PriceBreak
+	if err := db.Where("product_id IN ?", productIDs).
+		Order("product_id, min_quantity DESC").
+		Find(&breaks).Error; err != nil {
+		return nil, err
+	}
+	for _, b := range breaks {
+		result[b.ProductID] = append(result[b.ProductID], b)
+	}
+	return result, nil
+}
+
+// loadPriceLists returns the price lists a buyer has with the given sellers at
+// a point in time, keyed by seller id. Lists of grants that no longer give
+// access are ignored.
+func loadPriceLists(db *gorm.DB, buyerID uint, sellerIDs []uint, at time.Time) (map[uint]*models.PriceList, error) {
+	result := map[uint]*models.PriceList{}
+	if len(sellerIDs) == 0 {
+		return result, nil
+	}
+	var lists []models.PriceList
+	if err := db.Preload("Items").
+		Joins("JOIN permission_requests ON permission_requests.id = price_lists.permission_request_id AND "+activeGrantSQL).
+		Where("price_lists.buyer_id = ? AND price_lists.seller_id IN ?", buyerID, sellerIDs).
+		Where("(price_lists.effective_from IS NULL OR price_lists.effective_from <= ?) AND (price_lists.effective_to IS NULL OR price_lists.effective_to > ?)", at, at).
+		Order("price_lists.id").
+		Find(&lists).Error; err != nil {
+		return nil, err
+	}
+	// Effective dates of one relationship never overlap; keep the first list defensively.
+	for i := range lists {
+		if result[lists[i].SellerID] == nil {
+			result[lists[i].SellerID] = &lists[i]
+		}
+	}
+	return result, nil
+}
+
+// priceOrderItems computes the effective price of every item of a seller order.
+// Items must carry ListPrice and Quantity. Discounts apply in this order: the
+// buyer's price list, quantity price break, the buyer's standing discount, then
+// the coupon. Each discount is recorded on the item and LineTotal holds the result.
+func priceOrderItems(order *models.Order, priceList *models.PriceList, breaks map[uint][]models.PriceBreak, buyerPercent uint, coupon *models.Coupon) {
+	currency := order.Currency
+	for i := range order.OrderItems {
+		item := &order.OrderItems[i]
+		item.Discounts = nil
+
+		unit := item.ListPrice
+		if priceList != nil {
+			contract := priceList.UnitPrice(item.ProductID, unit, currency)
+			if contract < unit {
+				item.Discounts = append(item.Discounts, models.OrderItemDiscount{
+					Kind:        models.DiscountPriceList,
+					PriceListID: &priceList.ID,
+					Amount:      (unit - contract).Mul(item.Quantity),
+				})
+			}
+			unit = contract
+		}
+		for _, b := range breaks[item.ProductID] {
+			if b.MinQuantity <= item.Quantity {
+				if b.UnitPrice < unit {
+					item.Discounts = append(item.Discounts, models.OrderItemDiscount{
+						Kind:   models.DiscountPriceBreak,
+						Amount: (unit - b.UnitPrice).Mul(item.Quantity),
+					})
+					unit = b.UnitPrice
+				}
+				break
+			}
+		}
+		item.LineTotal = unit.Mul(item.Quantity)
+
+		if buyerPercent > 0 {
+			off := item.LineTotal.Percent(buyerPercent, currency)
+			item.LineTotal -= off
+			item.Discounts = append(item.Discounts, models.OrderItemDiscount{
+				Kind:    models.DiscountBuyer,
+				Percent: buyerPercent,
+				Amount:  off,
+			})
+		}
+
+		if coupon != nil && coupon.Type == models.CouponPercent {
+			off := item.LineTotal.Percent(coupon.Percent, currency)
+			item.LineTotal -= off
+			item.Discounts = append(item.Discounts, models.OrderItemDiscount{
+				Kind:     models.DiscountCoupon,
+				CouponID: &coupon.ID,
+				Code:     coupon.Code,
+				Percent:  coupon.Percent,
+				Amount:   off,
+			})
+		}
+	}
+
+	// A fixed coupon is spread over the lines in proportion to their totals;
+	// the last line takes the rounding remainder. Nothing is left to spread
+	// when earlier discounts have brought every line to zero.
+	if coupon != nil && coupon.Type == models.CouponFixed {
+		var net models.Money
+		for _, item := range order.OrderItems {
+			net += item.LineTotal
+		}
+		amount := coupon.Amount
+		if amount > net {
+			amount = net
+		}
+		remaining := amount
+		for i := range order.OrderItems {
+			item := &order.OrderItems[i]
+			off := remaining
+			if i < len(order.OrderItems)-1 && net > 0 {
+				off = mulDiv(amount, int64(item.LineTotal), int64(net)).Truncate(currency)
+			}
+			if off > item.LineTotal {
+				off = item.LineTotal
+			}
+			remaining -= off
+			item.LineTotal -= off
+			item.Discounts = append(item.Discounts, models.OrderItemDiscount{
+				Kind:     models.DiscountCoupon,
+				CouponID: &coupon.ID,
+				Code:     coupon.Code,
+				Amount:   off,
+			})
+		}
+	}
+
+	for i := range order.OrderItems {
+		item := &order.OrderItems[i]
+		item.Price = mulDiv(item.LineTotal, 1, int64(item.Quantity))
+	}
+}
+
+// orderDiscounts holds the pricing rules that apply to the orders of one checkout.
+type orderDiscounts struct {
+	priceLists   map[uint]*models.PriceList // by seller id
+	breaks       map[uint][]models.PriceBreak
+	buyerPercent map[uint]uint // by seller id
+	coupons      []*models.Coupon
+	bySeller     map[uint]*models.Coupon
+}
+
+// couponFor returns the coupon applying to an order, if any. Fixed coupons only
+// apply to orders in their own currency.
+func (d *orderDiscounts) couponFor(order *models.Order) *models.Coupon {
+	coupon := d.bySeller[order.SellerID]
+	if coupon == nil || (coupon.Type == models.CouponFixed && coupon.Currency != order.Currency) {
+		return nil
+	}
+	return coupon
+}
+
+// loadOrderDiscounts resolves the price lists, price breaks, buyer discounts and coupons
+// for the orders of a checkout. Invalid coupon codes are reported as *couponError.
+func loadOrderDiscounts(db *gorm.DB, buyerID uint, orders []models.Order, codes []string, at time.Time) (*orderDiscounts, error) {
+	d := &orderDiscounts{buyerPercent: map[uint]uint{}, bySeller: map[uint]*models.Coupon{}}
+
+	var productIDs, sellerIDs []uint
+	currencies := map[uint]map[string]bool{}
+	for _, order := range orders {
+		if currencies[order.SellerID] == nil {
+			sellerIDs = append(sellerIDs, order.SellerID)
+			currencies[order.SellerID] = map[string]bool{}
+		}
+		currencies[order.SellerID][order.Currency] = true
+		for _, item := range order.OrderItems {
+			productIDs = append(productIDs, item.ProductID)
+		}
+	}
+
+	var err error
+	if d.priceLists, err = loadPriceLists(db, buyerID, sellerIDs, at); err != nil {
+		return nil, err
+	}
+	if d.breaks, err = loadPriceBreaks(db, productIDs); err != nil {
+		return nil, err
+	}
+
+	var buyerDiscounts []models.BuyerDiscount
+	if err := db.Where("buyer_id = ? AND seller_id IN ?", buyerID, sellerIDs).Find(&buyerDiscounts).Error; err != nil {
+		return nil, err
+	}
+	for _, bd := range buyerDiscounts {
+		d.buyerPercent[bd.SellerID] = bd.Percent
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		code = normalizeCouponCode(code)
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+
+		var coupons []models.Coupon
+		if err := db.Where("code = ? AND seller_id IN ?", code, sellerIDs).Find(&coupons).Error; err != nil {
+			return nil, err
+		}
+		if len(coupons) == 0 {
+			return nil, &couponError{fmt.Sprintf("Coupon %s is not valid for the items in your cart", code)}
+		}
+		for i := range coupons {
+			coupon := &coupons[i]
+			if reason := couponUsable(coupon, at); reason != "" {
+				return nil, &couponError{reason}
+			}
+			if coupon.Type == models.CouponFixed && !currencies[coupon.SellerID][coupon.Currency] {
+				return nil, &couponError{fmt.Sprintf("Coupon %s only applies to prices in %s", code, coupon.Currency)}
+			}
+			if d.bySeller[coupon.SellerID] != nil {
+				return nil, &couponError{"Only one coupon can be used per supplier"}
+			}
+			d.bySeller[coupon.SellerID] = coupon
+			d.coupons = append(d.coupons, coupon)
+		}
+	}
+	return d, nil
+}
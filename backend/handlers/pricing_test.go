@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"backend/models"
+)
+
+// priced is the expected outcome for one item: its line total, unit price and
+// the discounts taken off it, in the order they applied.
+type priced struct {
+	lineTotal models.Money
+	price     models.Money
+	discounts []models.OrderItemDiscount
+}
+
+func TestPriceOrderItems(t *testing.T) {
+	yen := models.NewMoney
+	tests := []struct {
+		name         string
+		currency     string
+		items        []models.OrderItem
+		priceList    *models.PriceList
+		breaks       map[uint][]models.PriceBreak
+		buyerPercent uint
+		coupon       *models.Coupon
+		want         []priced
+	}{
+		{
+			name:     "list price",
+			currency: "JPY",
+			items:    []models.OrderItem{{ProductID: 1, Quantity: 3, ListPrice: yen(1000)}},
+			want:     []priced{{yen(3000), yen(1000), nil}},
+		},
+		{
+			name:      "contract price below a price break",
+			currency:  "JPY",
+			items:     []models.OrderItem{{ProductID: 1, Quantity: 10, ListPrice: yen(1000)}},
+			priceList: &models.PriceList{Items: []models.PriceListItem{{ProductID: 1, UnitPrice: yen(850)}}},
+			breaks:    map[uint][]models.PriceBreak{1: {{MinQuantity: 10, UnitPrice: yen(900)}}},
+			want: []priced{{yen(8500), yen(850), []models.OrderItemDiscount{
+				{Kind: models.DiscountPriceList, Amount: yen(1500)},
+			}}},
+		},
+		{
+			name:      "price break below the contract price",
+			currency:  "JPY",
+			items:     []models.OrderItem{{ProductID: 1, Quantity: 10, ListPrice: yen(1000)}},
+			priceList: &models.PriceList{Items: []models.PriceListItem{{ProductID: 1, UnitPrice: yen(850)}}},
+			breaks:    map[uint][]models.PriceBreak{1: {{MinQuantity: 10, UnitPrice: yen(800)}}},
+			want: []priced{{yen(8000), yen(800), []models.OrderItemDiscount{
+				{Kind: models.DiscountPriceList, Amount: yen(1500)},
+				{Kind: models.DiscountPriceBreak, Amount: yen(500)},
+			}}},
+		},
+		{
+			name:     "highest break reached",
+			currency: "JPY",
+			items:    []models.OrderItem{{ProductID: 1, Quantity: 20, ListPrice: yen(1000)}},
+			breaks: map[uint][]models.PriceBreak{1: {
+				{MinQuantity: 50, UnitPrice: yen(700)},
+				{MinQuantity: 10, UnitPrice: yen(900)},
+			}},
+			want: []priced{{yen(18000), yen(900), []models.OrderItemDiscount{
+				{Kind: models.DiscountPriceBreak, Amount: yen(2000)},
+			}}},
+		},
+		{
+			name:      "price list percentage rounded to whole yen",
+			currency:  "JPY",
+			items:     []models.OrderItem{{ProductID: 1, Quantity: 1, ListPrice: yen(999)}},
+			priceList: &models.PriceList{Percent: 10},
+			want: []priced{{yen(900), yen(900), []models.OrderItemDiscount{
+				{Kind: models.DiscountPriceList, Amount: yen(99)},
+			}}},
+		},
+		{
+			name:         "break, buyer discount then percent coupon",
+			currency:     "JPY",
+			items:        []models.OrderItem{{ProductID: 1, Quantity: 10, ListPrice: yen(1000)}},
+			breaks:       map[uint][]models.PriceBreak{1: {{MinQuantity: 10, UnitPrice: yen(900)}}},
+			buyerPercent: 5,
+			coupon:       &models.Coupon{Code: "TEN", Type: models.CouponPercent, Percent: 10},
+			want: []priced{{yen(7695), 76950, []models.OrderItemDiscount{
+				{Kind: models.DiscountPriceBreak, Amount: yen(1000)},
+				{Kind: models.DiscountBuyer, Percent: 5, Amount: yen(450)},
+				{Kind: models.DiscountCoupon, Code: "TEN", Percent: 10, Amount: yen(855)},
+			}}},
+		},
+		{
+			name:     "fixed coupon spread in proportion, last line takes the remainder",
+			currency: "JPY",
+			items: []models.OrderItem{
+				{ProductID: 1, Quantity: 1, ListPrice: yen(1000)},
+				{ProductID: 2, Quantity: 1, ListPrice: yen(2000)},
+			},
+			coupon: &models.Coupon{Code: "HUNDRED", Type: models.CouponFixed, Amount: yen(100)},
+			want: []priced{
+				{yen(967), yen(967), []models.OrderItemDiscount{{Kind: models.DiscountCoupon, Code: "HUNDRED", Amount: yen(33)}}},
+				{yen(1933), yen(1933), []models.OrderItemDiscount{{Kind: models.DiscountCoupon, Code: "HUNDRED", Amount: yen(67)}}},
+			},
+		},
+		{
+			name:     "fixed coupon larger than the cart",
+			currency: "JPY",
+			items:    []models.OrderItem{{ProductID: 1, Quantity: 2, ListPrice: yen(100)}},
+			coupon:   &models.Coupon{Code: "BIG", Type: models.CouponFixed, Amount: yen(500)},
+			want: []priced{{0, 0, []models.OrderItemDiscount{
+				{Kind: models.DiscountCoupon, Code: "BIG", Amount: yen(200)},
+			}}},
+		},
+		{
+			name:     "percent coupon in cents",
+			currency: "USD",
+			items:    []models.OrderItem{{ProductID: 1, Quantity: 3, ListPrice: 333}},
+			coupon:   &models.Coupon{Code: "FIFTEEN", Type: models.CouponPercent, Percent: 15},
+			want: []priced{{850, 283, []models.OrderItemDiscount{
+				{Kind: models.DiscountCoupon, Code: "FIFTEEN", Percent: 15, Amount: 149},
+			}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := models.Order{Currency: tt.currency, OrderItems: tt.items}
+			priceOrderItems(&order, tt.priceList, tt.breaks, tt.buyerPercent, tt.coupon)
+
+			for i, want := range tt.want {
+				item := order.OrderItems[i]
+				if item.LineTotal != want.lineTotal || item.Price != want.price {
+					t.Errorf("item %d: line total %s, price %s; want %s, %s", i, item.LineTotal, item.Price, want.lineTotal, want.price)
+				}
+				if len(item.Discounts) != len(want.discounts) {
+					t.Fatalf("item %d: %d discounts, want %d: %+v", i, len(item.Discounts), len(want.discounts), item.Discounts)
+				}
+				for j, d := range item.Discounts {
+					w := want.discounts[j]
+					if d.Kind != w.Kind || d.Code != w.Code || d.Percent != w.Percent || d.Amount != w.Amount {
+						t.Errorf("item %d discount %d = %s %s %d%% %s; want %s %s %d%% %s",
+							i, j, d.Kind, d.Code, d.Percent, d.Amount, w.Kind, w.Code, w.Percent, w.Amount)
+					}
+				}
+			}
+		})
+	}
+}
+
+// A fixed coupon on a cart that earlier discounts already brought to zero
+// takes nothing off and must not divide by the zero total.
+func TestPriceOrderItemsFixedCouponOnFullyDiscountedCart(t *testing.T) {
+	order := models.Order{
+		Currency: "JPY",
+		OrderItems: []models.OrderItem{
+			{ProductID: 1, Quantity: 2, ListPrice: 100000},
+			{ProductID: 2, Quantity: 1, ListPrice: 50000},
+		},
+	}
+	coupon := &models.Coupon{Code: "TAKE500", Type: models.CouponFixed, Amount: 50000}
+
+	priceOrderItems(&order, nil, nil, 100, coupon)
+
+	for _, item := range order.OrderItems {
+		if item.LineTotal != 0 || item.Price != 0 {
+			t.Errorf("product %d: line total %s, price %s; want 0", item.ProductID, item.LineTotal, item.Price)
+		}
+		for _, d := range item.Discounts {
+			if d.Kind == models.DiscountCoupon && d.Amount != 0 {
+				t.Errorf("product %d: coupon took %s off a zero line", item.ProductID, d.Amount)
+			}
+		}
+	}
+}
+
+func TestCouponUsable(t *testing.T) {
+	now := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+	before, after := now.Add(-time.Hour), now.Add(time.Hour)
+	tests := []struct {
+		name   string
+		coupon models.Coupon
+		usable bool
+	}{
+		{"active", models.Coupon{Active: true}, true},
+		{"inactive", models.Coupon{Active: false}, false},
+		{"within its window", models.Coupon{Active: true, ValidFrom: &before, ValidUntil: &after}, true},
+		{"not valid yet", models.Coupon{Active: true, ValidFrom: &after}, false},
+		{"expired", models.Coupon{Active: true, ValidUntil: &before}, false},
+		{"uses left", models.Coupon{Active: true, MaxUses: 2, UsedCount: 1}, true},
+		{"used up", models.Coupon{Active: true, MaxUses: 2, UsedCount: 2}, false},
+		{"unlimited", models.Coupon{Active: true, MaxUses: 0, UsedCount: 100}, true},
+	}
+	for _, tt := range tests {
+		if reason := couponUsable(&tt.coupon, now); (reason == "") != tt.usable {
+			t.Errorf("%s: couponUsable = %q, want usable %v", tt.name, reason, tt.usable)
+		}
+	}
+}
+
+func TestMulDiv(t *testing.T) {
+	tests := []struct {
+		m        models.Money
+		num, den int64
+		want     models.Money
+	}{
+		{10000, 1, 3, 3333},
+		{-10000, 1, 3, -3333},
+		{models.Money(1 << 62), 4, 8, models.Money(1 << 61)}, // m * num overflows int64
+	}
+	for _, tt := range tests {
+		if got := mulDiv(tt.m, tt.num, tt.den); got != tt.want {
+			t.Errorf("mulDiv(%d, %d, %d) = %d, want %d", tt.m, tt.num, tt.den, got, tt.want)
+		}
+	}
+}
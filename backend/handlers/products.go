@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -12,15 +15,164 @@ import (
 )
 
 // ProductResponse is the structure returned in the product list.
+// Quantity and Available are totals across all warehouses; Stocks holds the breakdown.
 type ProductResponse struct {
-	ID           uint    `json:"id"`
-	ProductName  string  `json:"product_name"`
-	Sku          string  `json:"sku"`
-	Price        float64 `json:"price"`
-	Quantity     uint    `json:"quantity"`
-	Description  string  `json:"description"`
-	Warehouse    string  `json:"warehouse"`
-	SupplierName string  `json:"supplier_name"`
+	ID          uint         `json:"id"`
+	ProductName string       `json:"product_name"`
+	Sku         string       `json:"sku"`
+	Price       models.Money `json:"price"`
+	Currency    string       `json:"currency"`
+	TaxCategory string       `json:"tax_category"`
+	Category    string       `json:"category"`
+	SupplierID  uint         `json:"supplier_id,omitempty"`
+	// Purchase catalog only: the buyer's contracted price from its price list,
+	// and that price converted into the buyer's currency at the current rate.
+	// BuyerPrice is omitted when no rate is available.
+	ContractPrice *models.Money    `gorm:"-" json:"contract_price,omitempty"`
+	BuyerPrice    *models.Money    `gorm:"-" json:"buyer_price,omitempty"`
+	BuyerCurrency string           `gorm:"-" json:"buyer_currency,omitempty"`
+	ExchangeRate  string           `gorm:"-" json:"exchange_rate,omitempty"`
+	Quantity      uint             `json:"quantity"`
+	Available     uint             `json:"available"`
+	Description   string           `json:"description"`
+	Warehouse     string           `json:"warehouse"`
+	SupplierName  string           `json:"supplier_name"`
+	Stocks        []WarehouseStock `gorm:"-" json:"stocks"`
+}
+
+// WarehouseStock is the stock of a product held in a single warehouse.
+type WarehouseStock struct {
+	ProductID        uint   `json:"-"`
+	WarehouseID      uint   `json:"warehouse_id"`
+	Warehouse        string `json:"warehouse"`
+	QuantityInStock  uint   `json:"quantity_in_stock"`
+	QuantityReserved uint   `json:"quantity_reserved"`
+	Available        uint   `json:"available"`
+}
+
+// loadWarehouseStocks returns the per-warehouse stock rows of the given products, keyed by product id.
+func loadWarehouseStocks(db *gorm.DB, productIDs []uint) (map[uint][]WarehouseStock, error) {
+	result := map[uint][]WarehouseStock{}
+	if len(productIDs) == 0 {
+		return result, nil
+	}
+
+	var rows []WarehouseStock
+	err := db.Table("inventory_stocks").
+		Select(`inventory_stocks.product_id, inventory_stocks.warehouse_id,
+                warehouses.warehouse_name as warehouse,
+                inventory_stocks.quantity_in_stock, inventory_stocks.quantity_reserved`).
+		Joins("LEFT JOIN warehouses ON inventory_stocks.warehouse_id = warehouses.id").
+		Where("inventory_stocks.deleted_at IS NULL AND inventory_stocks.product_id IN ?", productIDs).
+		Order("inventory_stocks.product_id, inventory_stocks.warehouse_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		if row.QuantityInStock > row.QuantityReserved {
+			row.Available = row.QuantityInStock - row.QuantityReserved
+		}
+		result[row.ProductID] = append(result[row.ProductID], row)
+	}
+	return result, nil
+}
+
+// attachWarehouseStocks fills the totals and per-warehouse breakdown of each product.
+func attachWarehouseStocks(db *gorm.DB, products []ProductResponse) error {
+	return attachSharedStocks(db, products, nil)
+}
+
+// attachSharedStocks is attachWarehouseStocks counting, for the products of
+// the sellers in shared, only the stock of the warehouses listed there.
+// Products must then carry their SupplierID.
+func attachSharedStocks(db *gorm.DB, products []ProductResponse, shared map[uint]map[uint]bool) error {
+	ids := make([]uint, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	stocks, err := loadWarehouseStocks(db, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range products {
+		p := &products[i]
+		p.Stocks = []WarehouseStock{}
+		for _, s := range stocks[p.ID] {
+			if warehouses, ok := shared[p.SupplierID]; !ok || warehouses[s.WarehouseID] {
+				p.Stocks = append(p.Stocks, s)
+			}
+		}
+		names := make([]string, 0, len(p.Stocks))
+		for _, s := range p.Stocks {
+			p.Quantity += s.QuantityInStock
+			p.Available += s.Available
+			names = append(names, s.Warehouse)
+		}
+		p.Warehouse = strings.Join(names, ", ")
+	}
+	return nil
+}
+
+// applyPriceLists sets the contracted price of each product the buyer has a
+// current price list for. Products must carry their SupplierID.
+func applyPriceLists(db *gorm.DB, buyerID uint, products []ProductResponse) error {
+	var sellerIDs []uint
+	seen := map[uint]bool{}
+	for _, p := range products {
+		if !seen[p.SupplierID] {
+			seen[p.SupplierID] = true
+			sellerIDs = append(sellerIDs, p.SupplierID)
+		}
+	}
+	lists, err := loadPriceLists(db, buyerID, sellerIDs, time.Now())
+	if err != nil {
+		return err
+	}
+	for i := range products {
+		p := &products[i]
+		if list := lists[p.SupplierID]; list != nil {
+			price := list.UnitPrice(p.ID, p.Price, p.Currency)
+			p.ContractPrice = &price
+		}
+	}
+	return nil
+}
+
+// convertCatalogPrices sets the buyer's price of each product in the buyer's
+// currency, starting from the contracted price when there is one. Products
+// without an exchange rate to that currency keep only their list price.
+func convertCatalogPrices(db *gorm.DB, buyerID uint, products []ProductResponse) error {
+	var buyer models.Companies
+	if err := db.Select("id", "default_currency").First(&buyer, buyerID).Error; err != nil {
+		return err
+	}
+
+	rates := newExchangeRateCache(db)
+	for i := range products {
+		p := &products[i]
+		rate, err := rates.rate(p.Currency, buyer.DefaultCurrency)
+		if errors.Is(err, errNoExchangeRate) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		base := p.Price
+		if p.ContractPrice != nil {
+			base = *p.ContractPrice
+		}
+		price, err := base.Convert(rate, buyer.DefaultCurrency)
+		if err != nil {
+			return err
+		}
+		p.BuyerPrice = &price
+		p.BuyerCurrency = buyer.DefaultCurrency
+		p.ExchangeRate = rate
+	}
+	return nil
 }
 
 // GetProductsHandler retrieves the product list for the owner.
@@ -41,13 +193,13 @@ func GetProductsHandler(db *gorm.DB) gin.HandlerFunc {
 		var products []ProductResponse
 		// Query only products owned by currentCompanyID.
 		err := db.Table("products").
-			Select(`products.id, products.product_name, products.sku, products.price, 
-                inventory_stocks.quantity_in_stock as quantity, products.description, 
-                warehouses.warehouse_name as warehouse`).
-			Joins("LEFT JOIN inventory_stocks ON inventory_stocks.product_id = products.id").
-			Joins("LEFT JOIN warehouses ON inventory_stocks.warehouse_id = warehouses.id").
+			Select("products.id, products.product_name, products.sku, products.price, products.currency, products.tax_category, products.category, products.description").
 			Where("products.deleted_at IS NULL AND products.supplier_id = ?", currentCompanyID).
+			Order("products.id").
 			Find(&products).Error
+		if err == nil {
+			err = attachWarehouseStocks(db, products)
+		}
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
@@ -62,13 +214,16 @@ func RegisterProductHandler(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Expected request payload.
 		var req struct {
-			ProductName          string  `json:"product_name"`
-			Description          string  `json:"description"`
-			Price                float64 `json:"price"`
-			Quantity             uint    `json:"quantity"`
-			WarehouseID          uint    `json:"warehouse_id"`
-			NewWarehouseName     string  `json:"new_warehouse_name"`
-			NewWarehouseLocation string  `json:"new_warehouse_location"`
+			ProductName          string       `json:"product_name"`
+			Description          string       `json:"description"`
+			Category             string       `json:"category"`
+			Price                models.Money `json:"price"`
+			TaxCategory          string       `json:"tax_category"` // defaults to "standard"
+			Currency             string       `json:"currency"`     // defaults to the company's currency
+			Quantity             uint         `json:"quantity"`
+			WarehouseID          uint         `json:"warehouse_id"`
+			NewWarehouseName     string       `json:"new_warehouse_name"`
+			NewWarehouseLocation string       `json:"new_warehouse_location"`
 		}
 
 		if err := c.BindJSON(&req); err != nil {
@@ -81,6 +236,23 @@ func RegisterProductHandler(db *gorm.DB) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required product fields or invalid values"})
 			return
 		}
+		req.Category = strings.TrimSpace(req.Category)
+		if len(req.Category) > 50 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Category must be at most 50 characters"})
+			return
+		}
+		if req.TaxCategory == "" {
+			req.TaxCategory = models.TaxCategoryStandard
+		}
+		if !validTaxCategory(req.TaxCategory) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tax category"})
+			return
+		}
+		req.Currency = strings.ToUpper(strings.TrimSpace(req.Currency))
+		if req.Currency != "" && !validCurrency(req.Currency) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency"})
+			return
+		}
 
 		// Get authenticated company id.
 		companyIDVal, exists := c.Get("companyID")
@@ -94,6 +266,15 @@ func RegisterProductHandler(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		if req.Currency == "" {
+			var company models.Companies
+			if err := db.Select("id", "default_currency").First(&company, supplierID).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch company"})
+				return
+			}
+			req.Currency = company.DefaultCurrency
+		}
+
 		var warehouseID uint = req.WarehouseID
 		var warehouseRecord models.Warehouse
 		// If warehouse_id is 0, add a new warehouse.
@@ -136,23 +317,43 @@ func RegisterProductHandler(db *gorm.DB) gin.HandlerFunc {
 			ProductName: req.ProductName,
 			Sku:         generatedSKU,
 			Description: req.Description,
+			Category:    req.Category,
 			SupplierID:  supplierID,
 			Price:       req.Price,
-			Status:      "active",
-		}
-		if err := db.Create(&product).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create product"})
-			return
+			Status:      models.ProductActive,
+			TaxCategory: req.TaxCategory,
+			Currency:    req.Currency,
 		}
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&product).Error; err != nil {
+				return err
+			}
 
-		// Create inventory stock record linked to the product.
-		stock := models.InventoryStock{
-			ProductID:       product.ID,
-			WarehouseID:     warehouseID,
-			QuantityInStock: req.Quantity,
-		}
-		if err := db.Create(&stock).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create inventory stock"})
+			// Create inventory stock record linked to the product.
+			stock := models.InventoryStock{
+				ProductID:   product.ID,
+				WarehouseID: warehouseID,
+			}
+			if err := tx.Create(&stock).Error; err != nil {
+				return err
+			}
+
+			// Record the initial quantity as a receipt in the ledger.
+			if req.Quantity == 0 {
+				return nil
+			}
+			return applyStockMovement(tx, &models.StockMovement{
+				ProductID:    product.ID,
+				WarehouseID:  warehouseID,
+				CompanyID:    supplierID,
+				MovementType: models.MovementReceipt,
+				ReasonCode:   "initial_stock",
+				Quantity:     int64(req.Quantity),
+				ActorID:      supplierID,
+				ActorEmail:   c.GetString("email"),
+			})
+		}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create product"})
 			return
 		}
 
@@ -175,22 +376,30 @@ func GetProductHandler(db *gorm.DB) gin.HandlerFunc {
 
 		var response struct {
 			models.Products
-			Quantity    uint   `json:"quantity"`
-			Warehouse   string `json:"warehouse"`
-			WarehouseID uint   `json:"warehouse_id"`
+			Quantity    uint             `json:"quantity"`
+			Available   uint             `json:"available"`
+			Warehouse   string           `json:"warehouse"`
+			WarehouseID uint             `json:"warehouse_id"` // first warehouse, kept for older clients
+			Stocks      []WarehouseStock `json:"stocks"`
 		}
 
-		err = db.Table("products").
-			Select("products.*, inventory_stocks.quantity_in_stock as quantity, warehouses.warehouse_name as warehouse, warehouses.id as warehouse_id").
-			Joins("LEFT JOIN inventory_stocks ON inventory_stocks.product_id = products.id").
-			Joins("LEFT JOIN warehouses ON inventory_stocks.warehouse_id = warehouses.id").
-			Where("products.id = ?", productID).
-			Scan(&response).Error
+		if err := db.First(&response.Products, productID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
 
-		if err != nil {
+		list := []ProductResponse{{ID: response.ID}}
+		if err := attachWarehouseStocks(db, list); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch product"})
 			return
 		}
+		response.Quantity = list[0].Quantity
+		response.Available = list[0].Available
+		response.Warehouse = list[0].Warehouse
+		response.Stocks = list[0].Stocks
+		if len(response.Stocks) > 0 {
+			response.WarehouseID = response.Stocks[0].WarehouseID
+		}
 		c.JSON(http.StatusOK, response)
 	}
 }
@@ -219,12 +428,18 @@ func UpdateProductHandler(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		var req struct {
-			ProductName string  `json:"product_name"`
-			Sku         string  `json:"sku"`
-			Description string  `json:"description"`
-			Price       float64 `json:"price"`
-			Quantity    uint    `json:"quantity"`
-			WarehouseID uint    `json:"warehouse_id"`
+			ProductName string       `json:"product_name"`
+			Sku         string       `json:"sku"`
+			Description string       `json:"description"`
+			Category    *string      `json:"category"` // unchanged when omitted
+			Price       models.Money `json:"price"`
+			TaxCategory string       `json:"tax_category"` // unchanged when empty
+			Currency    string       `json:"currency"`     // unchanged when empty
+			Status      string       `json:"status"`       // "active" or "inactive", unchanged when empty
+			Quantity    uint         `json:"quantity"`
+			WarehouseID uint         `json:"warehouse_id"`
+			ReasonCode  string       `json:"reason_code"`
+			Note        string       `json:"note"`
 		}
 
 		if err := c.BindJSON(&req); err != nil {
@@ -249,24 +464,170 @@ func UpdateProductHandler(db *gorm.DB) gin.HandlerFunc {
 		product.Sku = req.Sku
 		product.Description = req.Description
 		product.Price = req.Price
+		if req.Category != nil {
+			category := strings.TrimSpace(*req.Category)
+			if len(category) > 50 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Category must be at most 50 characters"})
+				return
+			}
+			product.Category = category
+		}
+		if req.TaxCategory != "" {
+			if !validTaxCategory(req.TaxCategory) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tax category"})
+				return
+			}
+			product.TaxCategory = req.TaxCategory
+		}
+		if req.Status != "" {
+			if req.Status != models.ProductActive && req.Status != models.ProductInactive {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Status must be 'active' or 'inactive'"})
+				return
+			}
+			product.Status = req.Status
+		}
+		currencyChanged := false
+		if req.Currency != "" {
+			req.Currency = strings.ToUpper(strings.TrimSpace(req.Currency))
+			if !validCurrency(req.Currency) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency"})
+				return
+			}
+			currencyChanged = req.Currency != product.Currency
+			product.Currency = req.Currency
+		}
 
-		if err := db.Save(&product).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product"})
-			return
+		// Moving stock to another warehouse requires owning that warehouse.
+		if req.WarehouseID > 0 {
+			var warehouse models.Warehouse
+			if err := db.First(&warehouse, req.WarehouseID).Error; err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid warehouse id"})
+				return
+			}
+			if warehouse.CompanyID != currentCompanyID {
+				c.JSON(http.StatusForbidden, gin.H{"error": "You can only move stock to your own warehouses"})
+				return
+			}
+		}
+
+		reasonCode := req.ReasonCode
+		if reasonCode == "" {
+			reasonCode = "manual_adjustment"
 		}
+		actorEmail := c.GetString("email")
 
-		// Update inventory stock.
-		var stock models.InventoryStock
-		if err := db.Where("product_id = ?", product.ID).First(&stock).Error; err != nil {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&product).Error; err != nil {
+				return err
+			}
+			// Price breaks and contracted price-list prices are in the product's
+			// currency and no longer apply; buyers with a price list get its
+			// percentage off the new list price instead.
+			if currencyChanged {
+				if err := tx.Where("product_id = ?", product.ID).Delete(&models.PriceBreak{}).Error; err != nil {
+					return err
+				}
+				if err := tx.Where("product_id = ?", product.ID).Delete(&models.PriceListItem{}).Error; err != nil {
+					return err
+				}
+			}
+
+			var stocks []models.InventoryStock
+			if err := tx.Where("product_id = ?", product.ID).Order("id").Find(&stocks).Error; err != nil {
+				return err
+			}
+			if len(stocks) == 0 {
+				return gorm.ErrRecordNotFound
+			}
+
+			warehouseID := req.WarehouseID
+			if warehouseID == 0 {
+				if len(stocks) > 1 {
+					// Without a warehouse the quantity is ambiguous; only an unchanged total is accepted.
+					var total uint
+					for _, stock := range stocks {
+						total += stock.QuantityInStock
+					}
+					if total == req.Quantity {
+						return nil
+					}
+					return errAmbiguousWarehouse
+				}
+				warehouseID = stocks[0].WarehouseID
+			}
+
+			stocked := false
+			for _, stock := range stocks {
+				if stock.WarehouseID == warehouseID {
+					stocked = true
+				}
+			}
+
+			// Pointing a single-warehouse product at another warehouse moves
+			// the whole balance there as a transfer pair.
+			if !stocked && len(stocks) == 1 {
+				stock := stocks[0]
+				current := int64(stock.QuantityInStock)
+				if current > 0 {
+					if err := applyStockMovement(tx, &models.StockMovement{
+						ProductID:    product.ID,
+						WarehouseID:  stock.WarehouseID,
+						CompanyID:    currentCompanyID,
+						MovementType: models.MovementTransfer,
+						ReasonCode:   "warehouse_change",
+						Quantity:     -current,
+						ActorID:      currentCompanyID,
+						ActorEmail:   actorEmail,
+					}); err != nil {
+						return err
+					}
+				}
+				if err := tx.Delete(&stock).Error; err != nil {
+					return err
+				}
+				if err := tx.Create(&models.InventoryStock{ProductID: product.ID, WarehouseID: warehouseID}).Error; err != nil {
+					return err
+				}
+				if current > 0 {
+					if err := applyStockMovement(tx, &models.StockMovement{
+						ProductID:    product.ID,
+						WarehouseID:  warehouseID,
+						CompanyID:    currentCompanyID,
+						MovementType: models.MovementTransfer,
+						ReasonCode:   "warehouse_change",
+						Quantity:     current,
+						ActorID:      currentCompanyID,
+						ActorEmail:   actorEmail,
+					}); err != nil {
+						return err
+					}
+				}
+			}
+
+			return setStockQuantity(tx, req.Quantity, models.StockMovement{
+				ProductID:   product.ID,
+				WarehouseID: warehouseID,
+				CompanyID:   currentCompanyID,
+				ReasonCode:  reasonCode,
+				ActorID:     currentCompanyID,
+				ActorEmail:  actorEmail,
+				Note:        req.Note,
+			})
+		})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Inventory record not found"})
 			return
 		}
-		stock.QuantityInStock = req.Quantity
-		if req.WarehouseID > 0 {
-			stock.WarehouseID = req.WarehouseID
+		if errors.Is(err, errInsufficientStock) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Quantity cannot be lower than the stock reserved by open orders"})
+			return
 		}
-		if err := db.Save(&stock).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update inventory record"})
+		if errors.Is(err, errAmbiguousWarehouse) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Product is stocked in several warehouses; specify warehouse_id or use the stock endpoint"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product"})
 			return
 		}
 
@@ -316,8 +677,167 @@ func DeleteProductHandler(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
+// GetProductStockHandler lists the per-warehouse stock of one of the company's products.
+func GetProductStockHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idParam := c.Param("id")
+		productID, err := strconv.Atoi(idParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product id"})
+			return
+		}
+
+		// Get authenticated company id.
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		currentCompanyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		var product models.Products
+		if err := db.First(&product, productID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+		if product.SupplierID != currentCompanyID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can only view stock of your own products"})
+			return
+		}
+
+		list := []ProductResponse{{ID: product.ID}}
+		if err := attachWarehouseStocks(db, list); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stock"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"product_id": product.ID,
+			"quantity":   list[0].Quantity,
+			"available":  list[0].Available,
+			"stocks":     list[0].Stocks,
+		})
+	}
+}
+
+// SetProductStockHandler sets the quantity of a product in one or more warehouses.
+// Each change is recorded in the stock ledger as an adjustment.
+// Expected JSON body: { "stocks": [{ "warehouse_id": 1, "quantity": 10 }], "reason_code": "...", "note": "..." }
+func SetProductStockHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idParam := c.Param("id")
+		productID, err := strconv.Atoi(idParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product id"})
+			return
+		}
+
+		// Get authenticated company id.
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		currentCompanyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		var req struct {
+			Stocks []struct {
+				WarehouseID uint `json:"warehouse_id"`
+				Quantity    uint `json:"quantity"`
+			} `json:"stocks"`
+			ReasonCode string `json:"reason_code"`
+			Note       string `json:"note"`
+		}
+		if err := c.BindJSON(&req); err != nil || len(req.Stocks) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+
+		var product models.Products
+		if err := db.First(&product, productID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+		if product.SupplierID != currentCompanyID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can only update your own products"})
+			return
+		}
+
+		// Every warehouse must belong to the company.
+		warehouseIDs := make([]uint, 0, len(req.Stocks))
+		for _, entry := range req.Stocks {
+			warehouseIDs = append(warehouseIDs, entry.WarehouseID)
+		}
+		var owned int64
+		if err := db.Model(&models.Warehouse{}).
+			Where("id IN ? AND company_id = ?", warehouseIDs, currentCompanyID).
+			Distinct("id").
+			Count(&owned).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch warehouses"})
+			return
+		}
+		distinct := map[uint]bool{}
+		for _, id := range warehouseIDs {
+			distinct[id] = true
+		}
+		if int(owned) != len(distinct) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid warehouse id"})
+			return
+		}
+
+		reasonCode := req.ReasonCode
+		if reasonCode == "" {
+			reasonCode = "manual_adjustment"
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			for _, entry := range req.Stocks {
+				if err := setStockQuantity(tx, entry.Quantity, models.StockMovement{
+					ProductID:   product.ID,
+					WarehouseID: entry.WarehouseID,
+					CompanyID:   currentCompanyID,
+					ReasonCode:  reasonCode,
+					ActorID:     currentCompanyID,
+					ActorEmail:  c.GetString("email"),
+					Note:        req.Note,
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if errors.Is(err, errInsufficientStock) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Quantity cannot be lower than the stock reserved by open orders"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update stock"})
+			return
+		}
+
+		list := []ProductResponse{{ID: product.ID}}
+		if err := attachWarehouseStocks(db, list); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stock"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"product_id": product.ID,
+			"quantity":   list[0].Quantity,
+			"available":  list[0].Available,
+			"stocks":     list[0].Stocks,
+		})
+	}
+}
+
 // GetPurchaseProductsHandler returns products from other companies
-// only if a permission request exists (with status "permitted") between the seller and the current buyer.
+// only if an active permission grant (status "permitted", not expired) exists between the seller and the current buyer.
 func GetPurchaseProductsHandler(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get authenticated company id (buyer).
@@ -334,18 +854,39 @@ func GetPurchaseProductsHandler(db *gorm.DB) gin.HandlerFunc {
 
 		var products []ProductResponse
 		// Return only products not owned by current user that have
-		// a matching permission request with status "permitted".
+		// a matching permission request with status "permitted" sharing them.
 		err := db.Table("products").
-			Select(`products.id, products.product_name, products.sku, products.price, 
-                inventory_stocks.quantity_in_stock as quantity, products.description, 
-                warehouses.warehouse_name as warehouse,
-                companies.name as supplier_name`).
-			Joins("LEFT JOIN inventory_stocks ON inventory_stocks.product_id = products.id").
-			Joins("LEFT JOIN warehouses ON inventory_stocks.warehouse_id = warehouses.id").
+			Select(`products.id, products.product_name, products.sku, products.price, products.currency, products.tax_category,
+                products.category, products.description, products.supplier_id, companies.name as supplier_name`).
 			Joins("LEFT JOIN companies ON companies.id = products.supplier_id").
-			Joins("JOIN permission_requests ON permission_requests.seller_id = products.supplier_id AND permission_requests.requester_id = ? AND permission_requests.status = ?", currentCompanyID, "permitted").
-			Where("products.deleted_at IS NULL AND products.supplier_id <> ?", currentCompanyID).
+			Joins("JOIN permission_requests ON permission_requests.seller_id = products.supplier_id AND permission_requests.requester_id = ? AND "+activeGrantSQL, currentCompanyID).
+			Where("products.deleted_at IS NULL AND products.status = ? AND products.supplier_id <> ?", models.ProductActive, currentCompanyID).
+			Where(sharedProductSQL).
+			Order("products.id").
 			Find(&products).Error
+		// Warehouse scopes also limit the stock the buyer sees.
+		var shared map[uint]map[uint]bool
+		if err == nil {
+			var sellerIDs []uint
+			seen := map[uint]bool{}
+			for _, p := range products {
+				if !seen[p.SupplierID] {
+					seen[p.SupplierID] = true
+					sellerIDs = append(sellerIDs, p.SupplierID)
+				}
+			}
+			shared, err = sharedWarehouses(db, currentCompanyID, sellerIDs)
+		}
+		if err == nil {
+			err = attachSharedStocks(db, products, shared)
+		}
+
+		if err == nil {
+			err = applyPriceLists(db, currentCompanyID, products)
+		}
+		if err == nil {
+			err = convertCatalogPrices(db, currentCompanyID, products)
+		}
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch purchase products"})
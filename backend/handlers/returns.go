@@ -0,0 +1,480 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"backend/models"
+)
+
+// errReturnStatusChanged is returned when a return left the expected status
+// between loading it and updating it.
+var errReturnStatusChanged = errors.New("return status changed")
+
+// errReturnExceedsShipped is returned when a return would cover more of an
+// order item than was shipped, counting the returns already requested.
+var errReturnExceedsShipped = errors.New("return exceeds shipped quantity")
+
+// CreateReturnHandler lets the buyer request a return of items from a delivered or completed order.
+// Expected JSON body: { "reason": "...", "lines": [{ "order_item_id": 1, "quantity": 2 }] }
+func CreateReturnHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idStr := c.Param("id")
+		orderID, err := strconv.Atoi(idStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order id"})
+			return
+		}
+
+		// Get authenticated company id.
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		var req struct {
+			Reason string `json:"reason"`
+			Lines  []struct {
+				OrderItemID uint `json:"order_item_id"`
+				Quantity    uint `json:"quantity"`
+			} `json:"lines"`
+		}
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+		req.Reason = strings.TrimSpace(req.Reason)
+		if req.Reason == "" || len(req.Lines) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A reason and at least one line are required"})
+			return
+		}
+
+		var order models.Order
+		if err := db.Preload("OrderItems").First(&order, orderID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+			return
+		}
+		if order.CompanyID != companyID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the buyer can return items of this order"})
+			return
+		}
+		if order.Status != models.OrderDelivered && order.Status != models.OrderCompleted {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only delivered or completed orders can be returned"})
+			return
+		}
+
+		items := map[uint]models.OrderItem{}
+		for _, item := range order.OrderItems {
+			items[item.ID] = item
+		}
+
+		ret := models.ReturnRequest{
+			OrderID:  order.ID,
+			BuyerID:  companyID,
+			SellerID: order.SellerID,
+			Status:   models.ReturnRequested,
+			Reason:   req.Reason,
+		}
+		requested := map[uint]uint{}
+		for _, line := range req.Lines {
+			item, found := items[line.OrderItemID]
+			if !found {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Order item %d does not belong to this order", line.OrderItemID)})
+				return
+			}
+			if line.Quantity == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Quantity must be greater than zero"})
+				return
+			}
+			requested[item.ID] += line.Quantity
+
+			// Orders placed before carts were split may mix suppliers;
+			// a return must then only cover a single supplier's items.
+			if order.SellerID == 0 {
+				var product models.Products
+				if err := db.Unscoped().Select("id", "supplier_id").First(&product, item.ProductID).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch product"})
+					return
+				}
+				if ret.SellerID == 0 {
+					ret.SellerID = product.SupplierID
+				} else if ret.SellerID != product.SupplierID {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "A return can only cover items from a single supplier"})
+					return
+				}
+			}
+
+			ret.Lines = append(ret.Lines, models.ReturnLine{
+				OrderItemID: item.ID,
+				ProductID:   item.ProductID,
+				Quantity:    line.Quantity,
+				Price:       item.Price,
+				TaxRate:     item.TaxRate,
+				Amount:      mulDiv(item.LineTotal, int64(line.Quantity), int64(item.Quantity)),
+			})
+		}
+
+		var overReturnedID uint
+		err = db.Transaction(func(tx *gorm.DB) error {
+			// Locking the order's items serialises concurrent returns of the
+			// order, so that they cannot together return more than was shipped.
+			var locked []models.OrderItem
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("order_id = ?", order.ID).Order("id").Find(&locked).Error; err != nil {
+				return err
+			}
+
+			// Quantities already covered by returns that were not rejected.
+			var returned []struct {
+				OrderItemID uint
+				Quantity    uint
+			}
+			if err := tx.Table("return_lines").
+				Select("return_lines.order_item_id, SUM(return_lines.quantity) as quantity").
+				Joins("JOIN return_requests ON return_requests.id = return_lines.return_id").
+				Where("return_requests.order_id = ? AND return_requests.status <> ? AND return_requests.deleted_at IS NULL AND return_lines.deleted_at IS NULL", order.ID, models.ReturnRejected).
+				Group("return_lines.order_item_id").
+				Scan(&returned).Error; err != nil {
+				return err
+			}
+			alreadyReturned := map[uint]uint{}
+			for _, r := range returned {
+				alreadyReturned[r.OrderItemID] = r.Quantity
+			}
+			for _, item := range locked {
+				if quantity, found := requested[item.ID]; found && alreadyReturned[item.ID]+quantity > item.QuantityShipped {
+					overReturnedID = item.ID
+					return errReturnExceedsShipped
+				}
+			}
+
+			return tx.Create(&ret).Error
+		})
+		if errors.Is(err, errReturnExceedsShipped) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Order item %d cannot be returned in that quantity", overReturnedID)})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create return request"})
+			return
+		}
+		c.JSON(http.StatusCreated, ret)
+	}
+}
+
+// GetReturnsHandler lists the returns where the authenticated company is buyer or seller.
+// Query parameters: role ("buyer" or "seller"), status
+func GetReturnsHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		query := db.Preload("Lines")
+		switch c.Query("role") {
+		case "buyer":
+			query = query.Where("buyer_id = ?", companyID)
+		case "seller":
+			query = query.Where("seller_id = ?", companyID)
+		case "":
+			query = query.Where("buyer_id = ? OR seller_id = ?", companyID, companyID)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be 'buyer' or 'seller'"})
+			return
+		}
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+
+		var returns []models.ReturnRequest
+		if err := query.Order("id DESC").Find(&returns).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch returns"})
+			return
+		}
+		c.JSON(http.StatusOK, returns)
+	}
+}
+
+// GetReturnHandler retrieves a single return for its buyer or seller.
+func GetReturnHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		returnID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid return id"})
+			return
+		}
+
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		var ret models.ReturnRequest
+		if err := db.Preload("Lines").First(&ret, returnID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Return not found"})
+			return
+		}
+		if ret.BuyerID != companyID && ret.SellerID != companyID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+		c.JSON(http.StatusOK, ret)
+	}
+}
+
+// ApproveReturnHandler lets the seller approve a requested return, fixing the refund amount.
+// Expected JSON body (optional): { "comment": "..." }
+func ApproveReturnHandler(db *gorm.DB) gin.HandlerFunc {
+	return returnDecisionHandler(db, models.ReturnApproved)
+}
+
+// RejectReturnHandler lets the seller reject a requested return.
+// Expected JSON body: { "comment": "..." }
+func RejectReturnHandler(db *gorm.DB) gin.HandlerFunc {
+	return returnDecisionHandler(db, models.ReturnRejected)
+}
+
+// returnDecisionHandler moves a requested return to the approved or rejected status.
+func returnDecisionHandler(db *gorm.DB, decision string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		returnID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid return id"})
+			return
+		}
+
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		var req struct {
+			Comment string `json:"comment"`
+		}
+		// The body is optional.
+		if c.Request.ContentLength > 0 {
+			if err := c.BindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+				return
+			}
+		}
+		req.Comment = strings.TrimSpace(req.Comment)
+		if decision == models.ReturnRejected && req.Comment == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A rejection comment is required"})
+			return
+		}
+
+		var ret models.ReturnRequest
+		if err := db.Preload("Lines").First(&ret, returnID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Return not found"})
+			return
+		}
+		if ret.SellerID != companyID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the seller can decide on this return"})
+			return
+		}
+		if ret.Status != models.ReturnRequested {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Return has already been decided"})
+			return
+		}
+
+		// The refund includes the tax charged on the returned items, rounded per rate.
+		var refund, refundTax models.Money
+		if decision == models.ReturnApproved {
+			var order models.Order
+			if err := db.Select("id", "currency").First(&order, ret.OrderID).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch order"})
+				return
+			}
+			taxable := map[uint]models.Money{}
+			for _, line := range ret.Lines {
+				taxable[line.TaxRate] += line.Amount
+			}
+			var subtotal models.Money
+			_, subtotal, refundTax = computeTaxLines(taxable, order.Currency)
+			refund = subtotal + refundTax
+		}
+
+		now := time.Now()
+		res := db.Model(&models.ReturnRequest{}).
+			Where("id = ? AND status = ?", ret.ID, models.ReturnRequested).
+			Updates(map[string]interface{}{
+				"status":         decision,
+				"seller_comment": req.Comment,
+				"refund_amount":  refund,
+				"refund_tax":     refundTax,
+				"decided_at":     now,
+			})
+		if res.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update return"})
+			return
+		}
+		if res.RowsAffected == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Return has already been decided"})
+			return
+		}
+
+		ret.Status = decision
+		ret.SellerComment = req.Comment
+		ret.RefundAmount = refund
+		ret.RefundTax = refundTax
+		ret.DecidedAt = &now
+		c.JSON(http.StatusOK, ret)
+	}
+}
+
+// ReceiveReturnHandler records the arrival of approved returned goods. The seller
+// either restocks them into one of its warehouses or writes them off.
+// Expected JSON body: { "disposition": "restock", "warehouse_id": 1 } or { "disposition": "write_off" }
+func ReceiveReturnHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		returnID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid return id"})
+			return
+		}
+
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		var req struct {
+			Disposition string `json:"disposition"`
+			WarehouseID uint   `json:"warehouse_id"`
+		}
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+		if req.Disposition != models.DispositionRestock && req.Disposition != models.DispositionWriteOff {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Disposition must be 'restock' or 'write_off'"})
+			return
+		}
+
+		var ret models.ReturnRequest
+		if err := db.Preload("Lines").First(&ret, returnID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Return not found"})
+			return
+		}
+		if ret.SellerID != companyID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the seller can receive this return"})
+			return
+		}
+		if ret.Status != models.ReturnApproved {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only approved returns can be received"})
+			return
+		}
+
+		if req.Disposition == models.DispositionRestock {
+			var warehouse models.Warehouse
+			if err := db.First(&warehouse, req.WarehouseID).Error; err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid warehouse id"})
+				return
+			}
+			if warehouse.CompanyID != companyID {
+				c.JSON(http.StatusForbidden, gin.H{"error": "You can only restock into your own warehouses"})
+				return
+			}
+		}
+
+		now := time.Now()
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&ret, ret.ID).Error; err != nil {
+				return err
+			}
+			if ret.Status != models.ReturnApproved {
+				return errReturnStatusChanged
+			}
+
+			fields := map[string]interface{}{
+				"status":      models.ReturnReceived,
+				"disposition": req.Disposition,
+				"received_at": now,
+			}
+			if req.Disposition == models.DispositionRestock {
+				fields["warehouse_id"] = req.WarehouseID
+			}
+			if err := tx.Model(&ret).Updates(fields).Error; err != nil {
+				return err
+			}
+
+			// Written-off goods never re-enter stock.
+			if req.Disposition != models.DispositionRestock {
+				return nil
+			}
+			orderID := ret.OrderID
+			for _, line := range ret.Lines {
+				if err := applyStockMovement(tx, &models.StockMovement{
+					ProductID:    line.ProductID,
+					WarehouseID:  req.WarehouseID,
+					CompanyID:    companyID,
+					MovementType: models.MovementReturn,
+					ReasonCode:   "customer_return",
+					Quantity:     int64(line.Quantity),
+					ActorID:      companyID,
+					ActorEmail:   c.GetString("email"),
+					OrderID:      &orderID,
+					Note:         fmt.Sprintf("return #%d", ret.ID),
+					OccurredAt:   now,
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if errors.Is(err, errReturnStatusChanged) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only approved returns can be received"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to receive return"})
+			return
+		}
+
+		if err := db.Preload("Lines").First(&ret, ret.ID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reload return"})
+			return
+		}
+		c.JSON(http.StatusOK, ret)
+	}
+}
@@ -9,7 +9,7 @@ import (
 	"backend/models"
 )
 
-// GetSalesHandler retrieves orders where at least one order item belongs to products supplied by the authenticated seller.
+// GetSalesHandler retrieves the orders sold by the authenticated seller.
 func GetSalesHandler(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get the authenticated seller's company ID from context.
@@ -25,12 +25,12 @@ func GetSalesHandler(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		var orders []models.Order
-		// Join orders with order_items and products.
-		// Only include orders where products.supplier_id equals the seller's company ID.
-		err := db.Preload("OrderItems").
+		// Orders carry their seller; orders placed before carts were split per
+		// supplier fall back to joining order_items with products.
+		err := db.Preload("OrderItems.Discounts").Preload("TaxLines").
 			Joins("JOIN order_items ON order_items.order_id = orders.id").
 			Joins("JOIN products ON products.id = order_items.product_id").
-			Where("products.supplier_id = ?", sellerID).
+			Where("orders.seller_id = ? OR (COALESCE(orders.seller_id, 0) = 0 AND products.supplier_id = ?)", sellerID, sellerID).
 			Group("orders.id").
 			Find(&orders).Error
 		if err != nil {
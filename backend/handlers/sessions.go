@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"backend/models"
+	"backend/utils"
+)
+
+// Lifetimes of the tokens issued at login. Access tokens are short lived and
+// renewed with the refresh token, which is replaced on every use.
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// errSessionEnded is returned when a refresh token no longer renews its session.
+var errSessionEnded = errors.New("session has ended")
+
+// startSession records a new session for the user and returns the login response.
+func startSession(c *gin.Context, db *gorm.DB, user *models.User) (gin.H, error) {
+	refreshToken, hash, err := utils.NewToken("")
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	session := models.Session{
+		UserID:           user.ID,
+		CompanyID:        user.CompanyID,
+		RefreshTokenHash: hash,
+		UserAgent:        userAgent,
+		IPAddress:        c.ClientIP(),
+		ExpiresAt:        now.Add(RefreshTokenTTL),
+		LastUsedAt:       now,
+	}
+	if err := db.Create(&session).Error; err != nil {
+		return nil, err
+	}
+	return sessionTokens(user, &session, refreshToken)
+}
+
+// sessionTokens returns a new access token for the session together with its refresh token.
+func sessionTokens(user *models.User, session *models.Session, refreshToken string) (gin.H, error) {
+	token, err := generateToken(*user, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	return gin.H{
+		"email":              user.Email,
+		"role":               user.Role,
+		"token":              token,
+		"expires_in":         int(AccessTokenTTL.Seconds()),
+		"refresh_token":      refreshToken,
+		"refresh_expires_at": session.ExpiresAt,
+	}, nil
+}
+
+// revokeSessions ends the sessions matching the condition that are still open.
+func revokeSessions(tx *gorm.DB, reason string, query string, args ...interface{}) error {
+	return tx.Model(&models.Session{}).
+		Where("revoked_at IS NULL").
+		Where(query, args...).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revocation_reason": reason}).Error
+}
+
+// RefreshTokenHandler exchanges a refresh token for a new access token and a
+// new refresh token. Presenting a refresh token that was already exchanged
+// revokes the session, since the token must have been copied.
+// Expected JSON body: { "refresh_token": "..." }
+func RefreshTokenHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody struct {
+			RefreshToken string `json:"refresh_token" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A refresh token is required"})
+			return
+		}
+		hash := utils.HashToken(reqBody.RefreshToken)
+
+		var session models.Session
+		if err := db.Where("refresh_token_hash = ? OR previous_token_hash = ?", hash, hash).First(&session).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+			return
+		}
+		if session.RefreshTokenHash != hash {
+			if err := revokeSessions(db, models.SessionTokenReuse, "id = ?", session.ID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+				return
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+			return
+		}
+
+		var user models.User
+		now := time.Now()
+		newToken, newHash, err := utils.NewToken("")
+		if err == nil {
+			err = db.Transaction(func(tx *gorm.DB) error {
+				// The user and company must still be active.
+				if err := tx.Joins("JOIN companies ON companies.id = users.company_id AND companies.deleted_at IS NULL AND companies.status = 'active'").
+					Where("users.id = ? AND users.status = ?", session.UserID, models.UserActive).
+					First(&user).Error; err != nil {
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						return errSessionEnded
+					}
+					return err
+				}
+				// Only one of two concurrent refreshes wins.
+				res := tx.Model(&session).
+					Where("refresh_token_hash = ? AND revoked_at IS NULL AND expires_at > ?", hash, now).
+					Updates(map[string]interface{}{
+						"previous_token_hash": hash,
+						"refresh_token_hash":  newHash,
+						"last_used_at":        now,
+					})
+				if res.Error != nil {
+					return res.Error
+				}
+				if res.RowsAffected == 0 {
+					return errSessionEnded
+				}
+				return nil
+			})
+		}
+		if errors.Is(err, errSessionEnded) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has ended"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+			return
+		}
+
+		response, err := sessionTokens(&user, &session, newToken)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// RevokeRefreshTokenHandler ends the session of a refresh token. It needs no
+// access token, so that clients can log out after their access token expired.
+// Expected JSON body: { "refresh_token": "..." }
+func RevokeRefreshTokenHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody struct {
+			RefreshToken string `json:"refresh_token" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A refresh token is required"})
+			return
+		}
+		// Unknown tokens are not reported, as the outcome is the same.
+		if err := revokeSessions(db, models.SessionLogout, "refresh_token_hash = ?", utils.HashToken(reqBody.RefreshToken)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+	}
+}
+
+// LogoutHandler ends the session of the access token used.
+func LogoutHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID := c.GetUint("sessionID")
+		if sessionID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Not logged in with a session"})
+			return
+		}
+		if err := revokeSessions(db, models.SessionLogout, "id = ?", sessionID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+	}
+}
+
+// LogoutAllHandler ends every session of the authenticated user, on all devices.
+func LogoutAllHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetUint("userID")
+		if userID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Not logged in with a session"})
+			return
+		}
+		if err := revokeSessions(db, models.SessionLogoutAll, "user_id = ?", userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Logged out of all sessions"})
+	}
+}
+
+// GetSessionsHandler lists the open sessions of the authenticated user, most recently used first.
+func GetSessionsHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessions := []models.Session{}
+		if err := db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", c.GetUint("userID"), time.Now()).
+			Order("last_used_at DESC").Find(&sessions).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+			return
+		}
+		c.JSON(http.StatusOK, sessions)
+	}
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
@@ -35,10 +37,15 @@ func GetSettingsHandler(db *gorm.DB) gin.HandlerFunc {
 
 // CompanyUpdateInput defines the payload for updating settings.
 type CompanyUpdateInput struct {
-	Name            string `json:"name" binding:"required"`
-	Address         string `json:"address" binding:"required"`
-	Phone           string `json:"phone" binding:"required"`
-	Email           string `json:"email" binding:"required,email"`
+	Name    string `json:"name" binding:"required"`
+	Address string `json:"address" binding:"required"`
+	Phone   string `json:"phone" binding:"required"`
+	Email   string `json:"email" binding:"required,email"`
+	// RegistrationNumber is the qualified invoice issuer number; empty clears it.
+	RegistrationNumber string `json:"registrationNumber"`
+	// DefaultCurrency is left unchanged when empty. It cannot change once the
+	// company has orders, whose amounts are reported in it.
+	DefaultCurrency string `json:"defaultCurrency"`
 	CurrentPassword string `json:"currentPassword" binding:"required"`
 }
 
@@ -62,32 +69,91 @@ func UpdateSettingsHandler(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		input.RegistrationNumber = strings.ToUpper(strings.TrimSpace(input.RegistrationNumber))
+		if input.RegistrationNumber != "" && !registrationNumberPattern.MatchString(input.RegistrationNumber) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Registration number must be T followed by 13 digits"})
+			return
+		}
+		input.DefaultCurrency = strings.ToUpper(strings.TrimSpace(input.DefaultCurrency))
+		if input.DefaultCurrency != "" && !validCurrency(input.DefaultCurrency) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency"})
+			return
+		}
+
 		var company models.Companies
 		if err := db.First(&company, companyID).Error; err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
 			return
 		}
 
-		// Validate current password.
-		if err := bcrypt.CompareHashAndPassword([]byte(company.PasswordHash), []byte(input.CurrentPassword)); err != nil {
+		// Validate the current password of the user making the change.
+		user, ok := currentUser(c, db)
+		if !ok {
+			return
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.CurrentPassword)); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid current password"})
 			return
 		}
 
+		if input.DefaultCurrency != "" && input.DefaultCurrency != company.DefaultCurrency {
+			hasOrders, err := companyHasOrders(db, companyID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update settings"})
+				return
+			}
+			if hasOrders {
+				c.JSON(http.StatusConflict, gin.H{"error": "The default currency cannot change once the company has orders"})
+				return
+			}
+		}
+
+		// A new email has to be confirmed again.
+		emailChanged := company.Email != input.Email
+		if emailChanged {
+			company.EmailVerifiedAt = nil
+		}
 		company.Name = input.Name
 		company.Address = input.Address
 		company.Phone = input.Phone
 		company.Email = input.Email
+		company.RegistrationNumber = input.RegistrationNumber
+		if input.DefaultCurrency != "" {
+			company.DefaultCurrency = input.DefaultCurrency
+		}
 
 		if err := db.Save(&company).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update settings"})
 			return
 		}
+		// The settings are saved even when the email cannot be sent; the
+		// link can be requested again.
+		if emailChanged {
+			if err := startEmailVerification(db, &company, user); err != nil {
+				log.Println("Error sending verification email:", err)
+			}
+		}
 
 		c.JSON(http.StatusOK, company)
 	}
 }
 
+// companyHasOrders reports whether the company bought or sold in any order,
+// including orders placed before carts were split per supplier. The cost
+// report sums these orders in the currency they snapshotted for the company.
+func companyHasOrders(db *gorm.DB, companyID uint) (bool, error) {
+	var count int64
+	err := db.Raw(`
+            SELECT COUNT(*) FROM orders o
+            WHERE o.company_id = ? OR o.seller_id = ?
+               OR (COALESCE(o.seller_id, 0) = 0 AND EXISTS (
+                   SELECT 1 FROM order_items oi JOIN products p ON oi.product_id = p.id
+                   WHERE oi.order_id = o.id AND oi.deleted_at IS NULL AND p.supplier_id = ?))`,
+		companyID, companyID, companyID).
+		Row().Scan(&count)
+	return count > 0, err
+}
+
 // CompanyChangePasswordInput defines the payload for changing the password.
 type CompanyChangePasswordInput struct {
 	CurrentPassword string `json:"currentPassword" binding:"required"`
@@ -95,17 +161,11 @@ type CompanyChangePasswordInput struct {
 	ConfirmPassword string `json:"confirmPassword" binding:"required,eqfield=NewPassword"`
 }
 
-// ChangeCompanyPasswordHandler updates the company's password.
+// ChangeCompanyPasswordHandler updates the password of the authenticated user.
 func ChangeCompanyPasswordHandler(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		companyIDVal, exists := c.Get("companyID")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-			return
-		}
-		companyID, ok := companyIDVal.(uint)
+		user, ok := currentUser(c, db)
 		if !ok {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company ID"})
 			return
 		}
 
@@ -115,14 +175,8 @@ func ChangeCompanyPasswordHandler(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		var company models.Companies
-		if err := db.First(&company, companyID).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
-			return
-		}
-
 		// Validate current password.
-		if err := bcrypt.CompareHashAndPassword([]byte(company.PasswordHash), []byte(input.CurrentPassword)); err != nil {
+		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.CurrentPassword)); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid current password"})
 			return
 		}
@@ -134,8 +188,14 @@ func ChangeCompanyPasswordHandler(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		company.PasswordHash = string(hashedPassword)
-		if err := db.Save(&company).Error; err != nil {
+		// Other sessions end; the one changing the password stays logged in.
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(user).Update("password_hash", string(hashedPassword)).Error; err != nil {
+				return err
+			}
+			return revokeSessions(tx, models.SessionPasswordChanged, "user_id = ? AND id <> ?", user.ID, c.GetUint("sessionID"))
+		})
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
 			return
 		}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"backend/models"
+)
+
+// shipmentRequestError is a validation failure detected while recording a shipment.
+type shipmentRequestError struct {
+	Message string
+}
+
+func (e *shipmentRequestError) Error() string {
+	return e.Message
+}
+
+// CreateShipmentHandler records a (possibly partial) shipment against an accepted order.
+// Stock is decremented for the shipped quantities and the order moves to
+// PartiallyShipped or Shipped depending on what remains to be shipped.
+// Expected JSON body:
+// { "carrier": "...", "tracking_number": "...", "lines": [{ "order_item_id": 1, "quantity": 2 }] }
+func CreateShipmentHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idStr := c.Param("id")
+		orderID, err := strconv.Atoi(idStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order id"})
+			return
+		}
+
+		// Get authenticated company id.
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		var req struct {
+			Carrier        string `json:"carrier"`
+			TrackingNumber string `json:"tracking_number"`
+			Lines          []struct {
+				OrderItemID uint `json:"order_item_id"`
+				Quantity    uint `json:"quantity"`
+			} `json:"lines"`
+		}
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+		if len(req.Lines) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "At least one line is required"})
+			return
+		}
+
+		var order models.Order
+		if err := db.First(&order, orderID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+			return
+		}
+		if !isOrderSeller(db, &order, companyID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the seller can ship this order"})
+			return
+		}
+
+		actorEmail := c.GetString("email")
+		shipment := models.Shipment{
+			OrderID:        order.ID,
+			SellerID:       companyID,
+			Carrier:        strings.TrimSpace(req.Carrier),
+			TrackingNumber: strings.TrimSpace(req.TrackingNumber),
+			ShippedAt:      time.Now(),
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			// Lock the order so concurrent shipments see each other's quantities.
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Preload("OrderItems.Discounts").Preload("TaxLines").
+				First(&order, order.ID).Error; err != nil {
+				return err
+			}
+			if order.Status != models.OrderProcessing && order.Status != models.OrderPartiallyShipped {
+				return &shipmentRequestError{"Only accepted orders can be shipped"}
+			}
+
+			items := map[uint]*models.OrderItem{}
+			for i := range order.OrderItems {
+				items[order.OrderItems[i].ID] = &order.OrderItems[i]
+			}
+
+			shipping := map[uint]uint{}
+			for _, line := range req.Lines {
+				item, found := items[line.OrderItemID]
+				if !found {
+					return &shipmentRequestError{fmt.Sprintf("Order item %d does not belong to this order", line.OrderItemID)}
+				}
+				if line.Quantity == 0 {
+					return &shipmentRequestError{"Quantity must be greater than zero"}
+				}
+				shipping[item.ID] += line.Quantity
+				if item.QuantityShipped+shipping[item.ID] > item.Quantity {
+					return &shipmentRequestError{fmt.Sprintf("Order item %d only has %d units left to ship", item.ID, item.Quantity-item.QuantityShipped)}
+				}
+				// Orders placed before carts were split may mix suppliers.
+				if order.SellerID == 0 {
+					var product models.Products
+					if err := tx.Select("id", "supplier_id").First(&product, item.ProductID).Error; err != nil {
+						return err
+					}
+					if product.SupplierID != companyID {
+						return &shipmentRequestError{fmt.Sprintf("Order item %d is not supplied by you", item.ID)}
+					}
+				}
+				shipment.Lines = append(shipment.Lines, models.ShipmentLine{
+					OrderItemID: item.ID,
+					ProductID:   item.ProductID,
+					Quantity:    line.Quantity,
+				})
+			}
+
+			if err := tx.Create(&shipment).Error; err != nil {
+				return err
+			}
+
+			note := fmt.Sprintf("shipment #%d", shipment.ID)
+			for _, line := range shipment.Lines {
+				item := items[line.OrderItemID]
+				if err := shipOrderItem(tx, item, line.Quantity, companyID, companyID, actorEmail, note); err != nil {
+					return err
+				}
+				item.QuantityShipped += line.Quantity
+				if err := tx.Model(item).Update("quantity_shipped", item.QuantityShipped).Error; err != nil {
+					return err
+				}
+			}
+
+			action := "ship"
+			for _, item := range order.OrderItems {
+				if item.QuantityShipped < item.Quantity {
+					action = "ship_partial"
+				}
+			}
+			return applyOrderTransition(tx, findOrderTransition(action), &transitionContext{
+				Order:      &order,
+				CompanyID:  companyID,
+				ActorEmail: actorEmail,
+				Comment:    note,
+				At:         shipment.ShippedAt,
+			})
+		})
+		var reqErr *shipmentRequestError
+		if errors.As(err, &reqErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": reqErr.Message})
+			return
+		}
+		if errors.Is(err, errInsufficientStock) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Insufficient stock to ship these quantities"})
+			return
+		}
+		if errors.Is(err, errOrderStatusChanged) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Order was updated concurrently, please retry"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create shipment"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"shipment": shipment, "order": order})
+	}
+}
+
+// GetShipmentsHandler lists the shipments of an order for its buyer or seller.
+func GetShipmentsHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idStr := c.Param("id")
+		orderID, err := strconv.Atoi(idStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order id"})
+			return
+		}
+
+		// Get authenticated company id.
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		var order models.Order
+		if err := db.First(&order, orderID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+			return
+		}
+		if order.CompanyID != companyID && !isOrderSeller(db, &order, companyID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+
+		var shipments []models.Shipment
+		if err := db.Preload("Lines").Where("order_id = ?", order.ID).Order("shipped_at, id").Find(&shipments).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipments"})
+			return
+		}
+		c.JSON(http.StatusOK, shipments)
+	}
+}
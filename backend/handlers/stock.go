@@ -0,0 +1,484 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"backend/models"
+)
+
+// errInsufficientStock is returned when a movement would take a balance below
+// the quantity already reserved for open orders.
+var errInsufficientStock = errors.New("insufficient stock")
+
+// errAmbiguousWarehouse is returned when a quantity is set on a product stocked
+// in several warehouses without saying which one.
+var errAmbiguousWarehouse = errors.New("warehouse is required for multi-warehouse products")
+
+// stockShortage describes an order line that cannot be covered by available stock.
+type stockShortage struct {
+	ProductID uint `json:"product_id"`
+	Requested uint `json:"requested"`
+	Available uint `json:"available"`
+}
+
+// insufficientStockError lists every line of an order that could not be reserved.
+type insufficientStockError struct {
+	Shortages []stockShortage
+}
+
+func (e *insufficientStockError) Error() string {
+	return errInsufficientStock.Error()
+}
+
+func (e *insufficientStockError) Unwrap() error {
+	return errInsufficientStock
+}
+
+// lockStock loads the stock row of a product in a warehouse with a row lock,
+// creating an empty row if it does not exist yet.
+func lockStock(tx *gorm.DB, productID, warehouseID uint) (models.InventoryStock, error) {
+	var stock models.InventoryStock
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_id = ? AND warehouse_id = ?", productID, warehouseID).
+		First(&stock).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		stock = models.InventoryStock{ProductID: productID, WarehouseID: warehouseID}
+		err = tx.Create(&stock).Error
+	}
+	return stock, err
+}
+
+// applyStockMovement appends a ledger row and updates the cached balance on the
+// matching InventoryStock row, creating that row if it does not exist yet.
+// The balance may never drop below the quantity reserved for open orders.
+// It must be called inside a transaction so the row lock is held until commit.
+func applyStockMovement(tx *gorm.DB, m *models.StockMovement) error {
+	stock, err := lockStock(tx, m.ProductID, m.WarehouseID)
+	if err != nil {
+		return err
+	}
+
+	balance := int64(stock.QuantityInStock) + m.Quantity
+	if balance < int64(stock.QuantityReserved) {
+		return errInsufficientStock
+	}
+
+	m.BalanceAfter = balance
+	if m.OccurredAt.IsZero() {
+		m.OccurredAt = time.Now()
+	}
+	if err := tx.Create(m).Error; err != nil {
+		return err
+	}
+	return tx.Model(&stock).Update("quantity_in_stock", uint(balance)).Error
+}
+
+// setStockQuantity records an adjustment that brings the stock of m.ProductID in
+// m.WarehouseID to quantity. The remaining fields of m describe the movement;
+// nothing is written when the quantity is unchanged.
+func setStockQuantity(tx *gorm.DB, quantity uint, m models.StockMovement) error {
+	stock, err := lockStock(tx, m.ProductID, m.WarehouseID)
+	if err != nil {
+		return err
+	}
+	delta := int64(quantity) - int64(stock.QuantityInStock)
+	if delta == 0 {
+		return nil
+	}
+	m.MovementType = models.MovementAdjustment
+	m.Quantity = delta
+	return applyStockMovement(tx, &m)
+}
+
+// reserveOrderStock locks the stock of every ordered product and reserves the
+// requested quantities, spreading a line across warehouses when needed. Only
+// the warehouses the seller shares with the buyer are used.
+// The order and its items must already be created within tx. If any line
+// cannot be covered, nothing is reserved and an *insufficientStockError is returned.
+func reserveOrderStock(tx *gorm.DB, order *models.Order) error {
+	shared, err := sharedWarehouses(tx, order.CompanyID, []uint{order.SellerID})
+	if err != nil {
+		return err
+	}
+	warehouses, restricted := shared[order.SellerID]
+
+	// Lock in product order so concurrent checkouts cannot deadlock.
+	items := make([]*models.OrderItem, len(order.OrderItems))
+	for i := range order.OrderItems {
+		items[i] = &order.OrderItems[i]
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ProductID < items[j].ProductID })
+
+	stocks := map[uint][]models.InventoryStock{}
+	remaining := map[uint]uint{} // available quantity per product not yet allocated
+	var shortages []stockShortage
+	for _, item := range items {
+		if _, loaded := stocks[item.ProductID]; !loaded {
+			var locked []models.InventoryStock
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("product_id = ?", item.ProductID).
+				Order("id").
+				Find(&locked).Error; err != nil {
+				return err
+			}
+			var rows []models.InventoryStock
+			for _, row := range locked {
+				if !restricted || warehouses[row.WarehouseID] {
+					rows = append(rows, row)
+				}
+			}
+			stocks[item.ProductID] = rows
+			var available uint
+			for _, row := range rows {
+				if row.QuantityInStock > row.QuantityReserved {
+					available += row.QuantityInStock - row.QuantityReserved
+				}
+			}
+			remaining[item.ProductID] = available
+		}
+		if remaining[item.ProductID] < item.Quantity {
+			shortages = append(shortages, stockShortage{
+				ProductID: item.ProductID,
+				Requested: item.Quantity,
+				Available: remaining[item.ProductID],
+			})
+			continue
+		}
+		remaining[item.ProductID] -= item.Quantity
+	}
+	if len(shortages) > 0 {
+		return &insufficientStockError{Shortages: shortages}
+	}
+
+	supplierIDs := map[uint]uint{}
+	for _, item := range items {
+		if _, ok := supplierIDs[item.ProductID]; !ok {
+			var product models.Products
+			if err := tx.Select("id", "supplier_id").First(&product, item.ProductID).Error; err != nil {
+				return err
+			}
+			supplierIDs[item.ProductID] = product.SupplierID
+		}
+
+		need := item.Quantity
+		rows := stocks[item.ProductID]
+		for i := range rows {
+			if need == 0 {
+				break
+			}
+			row := &rows[i]
+			if row.QuantityInStock <= row.QuantityReserved {
+				continue
+			}
+			take := row.QuantityInStock - row.QuantityReserved
+			if take > need {
+				take = need
+			}
+			row.QuantityReserved += take
+			need -= take
+
+			if err := tx.Model(row).Update("quantity_reserved", row.QuantityReserved).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&models.StockReservation{
+				OrderID:     order.ID,
+				OrderItemID: item.ID,
+				ProductID:   item.ProductID,
+				WarehouseID: row.WarehouseID,
+				CompanyID:   supplierIDs[item.ProductID],
+				Quantity:    take,
+				Status:      models.ReservationActive,
+			}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// shipOrderItem takes quantity units of an order item out of stock for a shipment.
+// Active reservations of the item are consumed first (splitting a reservation
+// when only part of it ships); any remainder is taken from unreserved stock of
+// the seller's warehouses. Each decrement is recorded as a shipment movement.
+func shipOrderItem(tx *gorm.DB, item *models.OrderItem, quantity uint, sellerID uint, actorID uint, actorEmail, note string) error {
+	orderID := item.OrderID
+	need := quantity
+
+	var reservations []models.StockReservation
+	if err := tx.Where("order_item_id = ? AND status = ?", item.ID, models.ReservationActive).
+		Order("warehouse_id").
+		Find(&reservations).Error; err != nil {
+		return err
+	}
+
+	for i := range reservations {
+		if need == 0 {
+			break
+		}
+		r := &reservations[i]
+		take := r.Quantity
+		if take > need {
+			take = need
+		}
+
+		stock, err := lockStock(tx, r.ProductID, r.WarehouseID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&stock).
+			Update("quantity_reserved", gorm.Expr("quantity_reserved - ?", take)).Error; err != nil {
+			return err
+		}
+
+		if take == r.Quantity {
+			if err := tx.Model(r).Update("status", models.ReservationConsumed).Error; err != nil {
+				return err
+			}
+		} else {
+			if err := tx.Model(r).Update("quantity", r.Quantity-take).Error; err != nil {
+				return err
+			}
+			consumed := *r
+			consumed.ID = 0
+			consumed.Quantity = take
+			consumed.Status = models.ReservationConsumed
+			if err := tx.Create(&consumed).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := applyStockMovement(tx, &models.StockMovement{
+			ProductID:    r.ProductID,
+			WarehouseID:  r.WarehouseID,
+			CompanyID:    r.CompanyID,
+			MovementType: models.MovementShipment,
+			ReasonCode:   "order_shipped",
+			Quantity:     -int64(take),
+			ActorID:      actorID,
+			ActorEmail:   actorEmail,
+			OrderID:      &orderID,
+			Note:         note,
+		}); err != nil {
+			return err
+		}
+		need -= take
+	}
+	if need == 0 {
+		return nil
+	}
+
+	// Nothing (or not enough) was reserved, e.g. for orders placed before reservations.
+	var rows []models.InventoryStock
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_id = ?", item.ProductID).
+		Order("id").
+		Find(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if need == 0 {
+			break
+		}
+		if row.QuantityInStock <= row.QuantityReserved {
+			continue
+		}
+		take := row.QuantityInStock - row.QuantityReserved
+		if take > need {
+			take = need
+		}
+		if err := applyStockMovement(tx, &models.StockMovement{
+			ProductID:    item.ProductID,
+			WarehouseID:  row.WarehouseID,
+			CompanyID:    sellerID,
+			MovementType: models.MovementShipment,
+			ReasonCode:   "order_shipped",
+			Quantity:     -int64(take),
+			ActorID:      actorID,
+			ActorEmail:   actorEmail,
+			OrderID:      &orderID,
+			Note:         note,
+		}); err != nil {
+			return err
+		}
+		need -= take
+	}
+	if need > 0 {
+		return errInsufficientStock
+	}
+	return nil
+}
+
+// releaseOrderReservations returns the active reservations of an order to
+// available stock without touching the on-hand quantity.
+func releaseOrderReservations(tx *gorm.DB, orderID uint) error {
+	var reservations []models.StockReservation
+	if err := tx.Where("order_id = ? AND status = ?", orderID, models.ReservationActive).
+		Order("product_id, warehouse_id").
+		Find(&reservations).Error; err != nil {
+		return err
+	}
+
+	for i := range reservations {
+		r := &reservations[i]
+		stock, err := lockStock(tx, r.ProductID, r.WarehouseID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&stock).
+			Update("quantity_reserved", gorm.Expr("quantity_reserved - ?", r.Quantity)).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(r).Update("status", models.ReservationReleased).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseTimeParam accepts either RFC 3339 timestamps or plain YYYY-MM-DD dates.
+// dateOnly reports whether the value carried no time component.
+func parseTimeParam(value string) (t time.Time, dateOnly bool, err error) {
+	if t, err = time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	t, err = time.ParseInLocation("2006-01-02", value, time.Local)
+	return t, true, err
+}
+
+// GetStockMovementsHandler lists ledger rows for the authenticated company.
+// Query parameters: product_id, warehouse_id, type, from, to
+func GetStockMovementsHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		query := db.Model(&models.StockMovement{}).Where("company_id = ?", companyID)
+
+		if productParam := c.Query("product_id"); productParam != "" {
+			productID, err := strconv.Atoi(productParam)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product id"})
+				return
+			}
+			query = query.Where("product_id = ?", productID)
+		}
+		if warehouseParam := c.Query("warehouse_id"); warehouseParam != "" {
+			warehouseID, err := strconv.Atoi(warehouseParam)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid warehouse id"})
+				return
+			}
+			query = query.Where("warehouse_id = ?", warehouseID)
+		}
+		if movementType := c.Query("type"); movementType != "" {
+			query = query.Where("movement_type = ?", movementType)
+		}
+		if fromParam := c.Query("from"); fromParam != "" {
+			from, _, err := parseTimeParam(fromParam)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date"})
+				return
+			}
+			query = query.Where("occurred_at >= ?", from)
+		}
+		if toParam := c.Query("to"); toParam != "" {
+			to, dateOnly, err := parseTimeParam(toParam)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date"})
+				return
+			}
+			// A plain date includes the whole day.
+			if dateOnly {
+				query = query.Where("occurred_at < ?", to.AddDate(0, 0, 1))
+			} else {
+				query = query.Where("occurred_at <= ?", to)
+			}
+		}
+
+		var movements []models.StockMovement
+		if err := query.Order("occurred_at, id").Find(&movements).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stock movements"})
+			return
+		}
+		c.JSON(http.StatusOK, movements)
+	}
+}
+
+// GetStockBalanceHandler reconstructs the stock balance of a product at a point in time
+// by summing the ledger. Query parameters: product_id (required), warehouse_id, at
+func GetStockBalanceHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		productID, err := strconv.Atoi(c.Query("product_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product id"})
+			return
+		}
+
+		at := time.Now()
+		if atParam := c.Query("at"); atParam != "" {
+			parsed, dateOnly, err := parseTimeParam(atParam)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid at date"})
+				return
+			}
+			// A plain date means the balance at the end of that day.
+			if dateOnly {
+				parsed = parsed.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			}
+			at = parsed
+		}
+
+		query := db.Model(&models.StockMovement{}).
+			Where("company_id = ? AND product_id = ? AND occurred_at <= ?", companyID, productID, at)
+
+		var warehouseID int
+		if warehouseParam := c.Query("warehouse_id"); warehouseParam != "" {
+			warehouseID, err = strconv.Atoi(warehouseParam)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid warehouse id"})
+				return
+			}
+			query = query.Where("warehouse_id = ?", warehouseID)
+		}
+
+		var balance int64
+		if err := query.Select("COALESCE(SUM(quantity),0)").Row().Scan(&balance); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate stock balance"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"product_id":   productID,
+			"warehouse_id": warehouseID,
+			"at":           at,
+			"balance":      balance,
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"regexp"
+	"sort"
+
+	"backend/models"
+)
+
+// registrationNumberPattern matches a qualified invoice issuer number.
+var registrationNumberPattern = regexp.MustCompile(`^T[0-9]{13}$`)
+
+// validTaxCategory reports whether category is a known consumption tax category.
+func validTaxCategory(category string) bool {
+	_, ok := models.TaxRates[category]
+	return ok
+}
+
+// taxRateOf returns the tax rate in percent of a product's category.
+// Products created before tax categories existed are taxed at the standard rate.
+func taxRateOf(product models.Products) uint {
+	if rate, ok := models.TaxRates[product.TaxCategory]; ok {
+		return rate
+	}
+	return models.TaxRates[models.TaxCategoryStandard]
+}
+
+// computeTaxLines turns the taxable amount of each rate into tax lines.
+// Tax is computed on the total of each rate and rounded down to the minor unit
+// of the currency (whole yen) once per rate, never per item.
+func computeTaxLines(taxable map[uint]models.Money, currency string) (lines []models.OrderTaxLine, subtotal, tax models.Money) {
+	rates := make([]uint, 0, len(taxable))
+	for rate := range taxable {
+		rates = append(rates, rate)
+	}
+	sort.Slice(rates, func(i, j int) bool { return rates[i] > rates[j] })
+
+	for _, rate := range rates {
+		amount := taxable[rate]
+		rateTax := amount.Percent(rate, currency)
+		lines = append(lines, models.OrderTaxLine{
+			TaxRate:       rate,
+			TaxableAmount: amount,
+			TaxAmount:     rateTax,
+		})
+		subtotal += amount
+		tax += rateTax
+	}
+	return lines, subtotal, tax
+}
+
+// applyOrderTax computes the per-rate tax breakdown of an order from its items
+// and sets Subtotal, TaxTotal, Total and TaxLines accordingly.
+func applyOrderTax(order *models.Order) {
+	taxable := map[uint]models.Money{}
+	for _, item := range order.OrderItems {
+		taxable[item.TaxRate] += item.LineTotal
+	}
+	order.TaxLines, order.Subtotal, order.TaxTotal = computeTaxLines(taxable, order.Currency)
+	order.Total = order.Subtotal + order.TaxTotal
+}
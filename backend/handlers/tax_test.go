@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"testing"
+
+	"backend/models"
+)
+
+func TestComputeTaxLines(t *testing.T) {
+	yen := models.NewMoney
+	tests := []struct {
+		name         string
+		taxable      map[uint]models.Money
+		currency     string
+		wantLines    []models.OrderTaxLine
+		wantSubtotal models.Money
+		wantTax      models.Money
+	}{
+		{
+			name:     "rounded down to whole yen once per rate, highest rate first",
+			taxable:  map[uint]models.Money{8: yen(999), 10: yen(1001)},
+			currency: "JPY",
+			wantLines: []models.OrderTaxLine{
+				{TaxRate: 10, TaxableAmount: yen(1001), TaxAmount: yen(100)}, // 100.1
+				{TaxRate: 8, TaxableAmount: yen(999), TaxAmount: yen(79)},    // 79.92
+			},
+			wantSubtotal: yen(2000),
+			wantTax:      yen(179),
+		},
+		{
+			name:         "rounded down to the cent",
+			taxable:      map[uint]models.Money{10: 1999},
+			currency:     "USD",
+			wantLines:    []models.OrderTaxLine{{TaxRate: 10, TaxableAmount: 1999, TaxAmount: 199}},
+			wantSubtotal: 1999,
+			wantTax:      199,
+		},
+		{
+			name:         "zero rate",
+			taxable:      map[uint]models.Money{0: yen(500)},
+			currency:     "JPY",
+			wantLines:    []models.OrderTaxLine{{TaxRate: 0, TaxableAmount: yen(500), TaxAmount: 0}},
+			wantSubtotal: yen(500),
+		},
+		{
+			name:     "nothing taxable",
+			taxable:  map[uint]models.Money{},
+			currency: "JPY",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, subtotal, tax := computeTaxLines(tt.taxable, tt.currency)
+			if subtotal != tt.wantSubtotal || tax != tt.wantTax {
+				t.Errorf("subtotal %s, tax %s; want %s, %s", subtotal, tax, tt.wantSubtotal, tt.wantTax)
+			}
+			if len(lines) != len(tt.wantLines) {
+				t.Fatalf("%d lines, want %d: %+v", len(lines), len(tt.wantLines), lines)
+			}
+			for i, line := range lines {
+				want := tt.wantLines[i]
+				if line.TaxRate != want.TaxRate || line.TaxableAmount != want.TaxableAmount || line.TaxAmount != want.TaxAmount {
+					t.Errorf("line %d = %d%% %s %s; want %d%% %s %s", i,
+						line.TaxRate, line.TaxableAmount, line.TaxAmount, want.TaxRate, want.TaxableAmount, want.TaxAmount)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyOrderTax(t *testing.T) {
+	order := models.Order{
+		Currency: "JPY",
+		OrderItems: []models.OrderItem{
+			{TaxRate: 10, LineTotal: models.NewMoney(605)},
+			{TaxRate: 8, LineTotal: models.NewMoney(250)},
+			{TaxRate: 10, LineTotal: models.NewMoney(396)},
+		},
+	}
+	applyOrderTax(&order)
+
+	// 10% of 605 + 396 is 100.1 yen, where rounding per item would give 60 + 39.
+	if order.Subtotal != models.NewMoney(1251) || order.TaxTotal != models.NewMoney(120) || order.Total != models.NewMoney(1371) {
+		t.Errorf("subtotal %s, tax %s, total %s; want 1251.00, 120.00, 1371.00", order.Subtotal, order.TaxTotal, order.Total)
+	}
+	if len(order.TaxLines) != 2 || order.TaxLines[0].TaxRate != 10 || order.TaxLines[1].TaxRate != 8 {
+		t.Errorf("tax lines = %+v, want 10%% then 8%%", order.TaxLines)
+	}
+}
+
+func TestTaxRateOf(t *testing.T) {
+	tests := []struct {
+		category string
+		want     uint
+	}{
+		{models.TaxCategoryStandard, models.TaxRates[models.TaxCategoryStandard]},
+		{models.TaxCategoryReduced, models.TaxRates[models.TaxCategoryReduced]},
+		{"", models.TaxRates[models.TaxCategoryStandard]},
+	}
+	for _, tt := range tests {
+		if got := taxRateOf(models.Products{TaxCategory: tt.category}); got != tt.want {
+			t.Errorf("taxRateOf(%q) = %d, want %d", tt.category, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,372 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"backend/models"
+)
+
+// errTransferStatusChanged is returned when a transfer left the expected status
+// between loading it and updating it.
+var errTransferStatusChanged = errors.New("transfer status changed")
+
+// GetStockTransfersHandler lists the stock transfers of the authenticated company.
+// Query parameters: status
+func GetStockTransfersHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		query := db.Preload("Lines").Where("company_id = ?", companyID)
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+
+		var transfers []models.StockTransfer
+		if err := query.Order("id DESC").Find(&transfers).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transfers"})
+			return
+		}
+		c.JSON(http.StatusOK, transfers)
+	}
+}
+
+// GetStockTransferHandler retrieves a single stock transfer by id.
+func GetStockTransferHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		transferID, err := strconv.Atoi(c.Param("transferId"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transfer id"})
+			return
+		}
+
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		var transfer models.StockTransfer
+		if err := db.Preload("Lines").First(&transfer, transferID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Transfer not found"})
+			return
+		}
+		if transfer.CompanyID != companyID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+		c.JSON(http.StatusOK, transfer)
+	}
+}
+
+// CreateStockTransferHandler creates a draft transfer between two of the company's warehouses.
+// Expected JSON body:
+// { "source_warehouse_id": 1, "destination_warehouse_id": 2, "note": "...", "lines": [{ "product_id": 5, "quantity": 10 }] }
+func CreateStockTransferHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			SourceWarehouseID      uint   `json:"source_warehouse_id"`
+			DestinationWarehouseID uint   `json:"destination_warehouse_id"`
+			Note                   string `json:"note"`
+			Lines                  []struct {
+				ProductID uint `json:"product_id"`
+				Quantity  uint `json:"quantity"`
+			} `json:"lines"`
+		}
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+		if len(req.Lines) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "At least one line is required"})
+			return
+		}
+		if req.SourceWarehouseID == req.DestinationWarehouseID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Source and destination warehouses must differ"})
+			return
+		}
+
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		// Both warehouses must belong to the caller's company.
+		for _, warehouseID := range []uint{req.SourceWarehouseID, req.DestinationWarehouseID} {
+			var wh models.Warehouse
+			if err := db.First(&wh, warehouseID).Error; err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid warehouse id"})
+				return
+			}
+			if wh.CompanyID != companyID {
+				c.JSON(http.StatusForbidden, gin.H{"error": "You can only transfer between your own warehouses"})
+				return
+			}
+		}
+
+		lines := make([]models.StockTransferLine, 0, len(req.Lines))
+		for _, line := range req.Lines {
+			if line.Quantity == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Quantity must be greater than zero"})
+				return
+			}
+			var product models.Products
+			if err := db.First(&product, line.ProductID).Error; err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Product not found"})
+				return
+			}
+			if product.SupplierID != companyID {
+				c.JSON(http.StatusForbidden, gin.H{"error": "You can only transfer your own products"})
+				return
+			}
+			lines = append(lines, models.StockTransferLine{
+				ProductID: line.ProductID,
+				Quantity:  line.Quantity,
+			})
+		}
+
+		transfer := models.StockTransfer{
+			CompanyID:              companyID,
+			SourceWarehouseID:      req.SourceWarehouseID,
+			DestinationWarehouseID: req.DestinationWarehouseID,
+			Status:                 models.TransferDraft,
+			Note:                   req.Note,
+			Lines:                  lines,
+		}
+		if err := db.Create(&transfer).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transfer"})
+			return
+		}
+		c.JSON(http.StatusCreated, transfer)
+	}
+}
+
+// DispatchStockTransferHandler ships a draft transfer, taking its lines out of the source warehouse.
+func DispatchStockTransferHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		transferID, err := strconv.Atoi(c.Param("transferId"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transfer id"})
+			return
+		}
+
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		var transfer models.StockTransfer
+		if err := db.Preload("Lines").First(&transfer, transferID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Transfer not found"})
+			return
+		}
+		if transfer.CompanyID != companyID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+		if transfer.Status != models.TransferDraft {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Transfer is not in draft state"})
+			return
+		}
+
+		now := time.Now()
+		err = db.Transaction(func(tx *gorm.DB) error {
+			res := tx.Model(&models.StockTransfer{}).
+				Where("id = ? AND status = ?", transfer.ID, models.TransferDraft).
+				Updates(map[string]interface{}{"status": models.TransferInTransit, "dispatched_at": now})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return errTransferStatusChanged
+			}
+			for _, line := range transfer.Lines {
+				tid := transfer.ID
+				if err := applyStockMovement(tx, &models.StockMovement{
+					ProductID:    line.ProductID,
+					WarehouseID:  transfer.SourceWarehouseID,
+					CompanyID:    companyID,
+					MovementType: models.MovementTransfer,
+					ReasonCode:   "transfer_dispatch",
+					Quantity:     -int64(line.Quantity),
+					ActorID:      companyID,
+					ActorEmail:   c.GetString("email"),
+					TransferID:   &tid,
+					OccurredAt:   now,
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if errors.Is(err, errTransferStatusChanged) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Transfer is not in draft state"})
+			return
+		}
+		if errors.Is(err, errInsufficientStock) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Insufficient stock in source warehouse"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to dispatch transfer"})
+			return
+		}
+
+		transfer.Status = models.TransferInTransit
+		transfer.DispatchedAt = &now
+		c.JSON(http.StatusOK, transfer)
+	}
+}
+
+// ReceiveStockTransferHandler books an in-transit transfer into the destination warehouse.
+func ReceiveStockTransferHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		transferID, err := strconv.Atoi(c.Param("transferId"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transfer id"})
+			return
+		}
+
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		var transfer models.StockTransfer
+		if err := db.Preload("Lines").First(&transfer, transferID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Transfer not found"})
+			return
+		}
+		if transfer.CompanyID != companyID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+		if transfer.Status != models.TransferInTransit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Transfer is not in transit"})
+			return
+		}
+
+		now := time.Now()
+		err = db.Transaction(func(tx *gorm.DB) error {
+			res := tx.Model(&models.StockTransfer{}).
+				Where("id = ? AND status = ?", transfer.ID, models.TransferInTransit).
+				Updates(map[string]interface{}{"status": models.TransferReceived, "received_at": now})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return errTransferStatusChanged
+			}
+			for _, line := range transfer.Lines {
+				tid := transfer.ID
+				if err := applyStockMovement(tx, &models.StockMovement{
+					ProductID:    line.ProductID,
+					WarehouseID:  transfer.DestinationWarehouseID,
+					CompanyID:    companyID,
+					MovementType: models.MovementTransfer,
+					ReasonCode:   "transfer_receipt",
+					Quantity:     int64(line.Quantity),
+					ActorID:      companyID,
+					ActorEmail:   c.GetString("email"),
+					TransferID:   &tid,
+					OccurredAt:   now,
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if errors.Is(err, errTransferStatusChanged) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Transfer is not in transit"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to receive transfer"})
+			return
+		}
+
+		transfer.Status = models.TransferReceived
+		transfer.ReceivedAt = &now
+		c.JSON(http.StatusOK, transfer)
+	}
+}
+
+// DeleteStockTransferHandler discards a transfer that has not been dispatched yet.
+func DeleteStockTransferHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		transferID, err := strconv.Atoi(c.Param("transferId"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transfer id"})
+			return
+		}
+
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		var transfer models.StockTransfer
+		if err := db.First(&transfer, transferID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Transfer not found"})
+			return
+		}
+		if transfer.CompanyID != companyID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+
+		res := db.Where("id = ? AND status = ?", transfer.ID, models.TransferDraft).Delete(&models.StockTransfer{})
+		if res.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete transfer"})
+			return
+		}
+		if res.RowsAffected == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only draft transfers can be deleted"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Transfer deleted successfully"})
+	}
+}
@@ -0,0 +1,450 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"backend/models"
+	"backend/utils"
+)
+
+// InvitationTTL is how long an invitation can be accepted.
+const InvitationTTL = 7 * 24 * time.Hour
+
+// errLastOwner is returned when a change would leave a company without an active owner.
+var errLastOwner = errors.New("company must keep an active owner")
+
+// currentUser loads the authenticated user. It writes the error response and
+// returns false when the user cannot be loaded.
+func currentUser(c *gin.Context, db *gorm.DB) (*models.User, bool) {
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+	userID, ok := userIDVal.(uint)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user id"})
+		return nil, false
+	}
+
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	return &user, true
+}
+
+// canManageRole reports whether a user with the actor role may give a user the
+// target role, or change a user who has it. Only owners manage owners.
+func canManageRole(actor, target string) bool {
+	return target != models.RoleOwner || actor == models.RoleOwner
+}
+
+// GetCurrentUserHandler returns the authenticated user.
+func GetCurrentUserHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c, db)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, user)
+	}
+}
+
+// GetUsersHandler lists the users of the authenticated company.
+func GetUsersHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		users := []models.User{}
+		if err := db.Where("company_id = ?", companyID).Order("id").Find(&users).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+			return
+		}
+		c.JSON(http.StatusOK, users)
+	}
+}
+
+// companyUser loads the user named by the id parameter within the authenticated
+// company and checks that the authenticated user may manage it. It writes the
+// error response and returns nil when it may not.
+func companyUser(c *gin.Context, db *gorm.DB) *models.User {
+	companyIDVal, exists := c.Get("companyID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil
+	}
+	companyID, ok := companyIDVal.(uint)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+		return nil
+	}
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return nil
+	}
+	var user models.User
+	if err := db.Where("id = ? AND company_id = ?", userID, companyID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil
+	}
+	if !canManageRole(c.GetString("role"), user.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only an owner can change an owner"})
+		return nil
+	}
+	return &user
+}
+
+// keepOwner locks the active owners of a company and fails with errLastOwner
+// when the given user is the only one left.
+func keepOwner(tx *gorm.DB, user *models.User) error {
+	if user.Role != models.RoleOwner || user.Status != models.UserActive {
+		return nil
+	}
+	var owners []models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("company_id = ? AND role = ? AND status = ?", user.CompanyID, models.RoleOwner, models.UserActive).
+		Find(&owners).Error; err != nil {
+		return err
+	}
+	for _, owner := range owners {
+		if owner.ID != user.ID {
+			return nil
+		}
+	}
+	return errLastOwner
+}
+
+// UpdateUserHandler changes the role or status of a user of the company.
+// Expected JSON body: { "role": "warehouse", "status": "active" }
+func UpdateUserHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := companyUser(c, db)
+		if user == nil {
+			return
+		}
+
+		var reqBody struct {
+			Role   string `json:"role"`
+			Status string `json:"status"`
+		}
+		if err := c.BindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		fields := map[string]interface{}{}
+		if reqBody.Role != "" {
+			if !models.ValidRole(reqBody.Role) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be one of " + strings.Join(models.Roles, ", ")})
+				return
+			}
+			if !canManageRole(c.GetString("role"), reqBody.Role) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Only an owner can grant the owner role"})
+				return
+			}
+			fields["role"] = reqBody.Role
+		}
+		if reqBody.Status != "" {
+			if reqBody.Status != models.UserActive && reqBody.Status != models.UserDisabled {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Status must be 'active' or 'disabled'"})
+				return
+			}
+			fields["status"] = reqBody.Status
+		}
+		if len(fields) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Role or status is required"})
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if (reqBody.Role != "" && reqBody.Role != models.RoleOwner) || reqBody.Status == models.UserDisabled {
+				if err := keepOwner(tx, user); err != nil {
+					return err
+				}
+			}
+			// Access tokens carry the role, so the user has to log in again.
+			if (reqBody.Role != "" && reqBody.Role != user.Role) || reqBody.Status == models.UserDisabled {
+				if err := revokeSessions(tx, models.SessionUserChanged, "user_id = ?", user.ID); err != nil {
+					return err
+				}
+			}
+			return tx.Model(user).Updates(fields).Error
+		})
+		if errors.Is(err, errLastOwner) {
+			c.JSON(http.StatusConflict, gin.H{"error": "The company must keep at least one active owner"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+			return
+		}
+		c.JSON(http.StatusOK, user)
+	}
+}
+
+// DeleteUserHandler removes a user from the company.
+func DeleteUserHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := companyUser(c, db)
+		if user == nil {
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := keepOwner(tx, user); err != nil {
+				return err
+			}
+			if err := revokeSessions(tx, models.SessionUserChanged, "user_id = ?", user.ID); err != nil {
+				return err
+			}
+			return tx.Delete(user).Error
+		})
+		if errors.Is(err, errLastOwner) {
+			c.JSON(http.StatusConflict, gin.H{"error": "The company must keep at least one active owner"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "User deleted"})
+	}
+}
+
+// GetInvitationsHandler lists the company's invitations that have not been accepted.
+func GetInvitationsHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		invitations := []models.UserInvitation{}
+		if err := db.Where("company_id = ? AND accepted_at IS NULL", companyID).
+			Order("created_at DESC").Find(&invitations).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+			return
+		}
+		c.JSON(http.StatusOK, invitations)
+	}
+}
+
+// CreateInvitationHandler invites a person to join the company with a role.
+// The token is emailed to the person and only returned here; it is accepted with AcceptInvitationHandler.
+// A new invitation replaces a pending one for the same email.
+// Expected JSON body: { "email": "picker@example.com", "role": "warehouse" }
+func CreateInvitationHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		var reqBody struct {
+			Email string `json:"email" binding:"required,email"`
+			Role  string `json:"role" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A valid email and a role are required"})
+			return
+		}
+		reqBody.Email = strings.TrimSpace(reqBody.Email)
+		if !models.ValidRole(reqBody.Role) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be one of " + strings.Join(models.Roles, ", ")})
+			return
+		}
+		if !canManageRole(c.GetString("role"), reqBody.Role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only an owner can invite an owner"})
+			return
+		}
+
+		var count int64
+		if err := db.Model(&models.User{}).Where("email = ?", reqBody.Email).Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing users"})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "A user with this email already exists"})
+			return
+		}
+
+		token, hash, err := utils.NewToken("")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+			return
+		}
+		invitation := models.UserInvitation{
+			CompanyID:       companyID,
+			Email:           reqBody.Email,
+			Role:            reqBody.Role,
+			TokenHash:       hash,
+			InvitedByUserID: c.GetUint("userID"),
+			ExpiresAt:       time.Now().Add(InvitationTTL),
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("company_id = ? AND email = ? AND accepted_at IS NULL", companyID, reqBody.Email).
+				Delete(&models.UserInvitation{}).Error; err != nil {
+				return err
+			}
+			return tx.Create(&invitation).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+			return
+		}
+
+		// The token is also returned, so that the invitation can be passed on when the email is lost.
+		emailSent := false
+		var company models.Companies
+		if err := db.First(&company, companyID).Error; err == nil {
+			if err := sendInvitationEmail(&company, &invitation, token); err != nil {
+				log.Println("Error sending invitation email:", err)
+			} else {
+				emailSent = true
+			}
+		}
+		c.JSON(http.StatusCreated, gin.H{"invitation": invitation, "token": token, "email_sent": emailSent})
+	}
+}
+
+// DeleteInvitationHandler cancels a pending invitation.
+func DeleteInvitationHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		companyIDVal, exists := c.Get("companyID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		companyID, ok := companyIDVal.(uint)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid company id"})
+			return
+		}
+
+		invitationID, err := strconv.Atoi(c.Param("invitationId"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation id"})
+			return
+		}
+		res := db.Where("id = ? AND company_id = ? AND accepted_at IS NULL", invitationID, companyID).
+			Delete(&models.UserInvitation{})
+		if res.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete invitation"})
+			return
+		}
+		if res.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Invitation deleted"})
+	}
+}
+
+// AcceptInvitationHandler creates the invited user. It needs no authentication;
+// the token proves the invitation.
+// Expected JSON body: { "token": "...", "name": "Hanako Sato", "password": "..." }
+func AcceptInvitationHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody struct {
+			Token    string `json:"token" binding:"required"`
+			Name     string `json:"name"`
+			Password string `json:"password" binding:"required,min=8"`
+		}
+		if err := c.ShouldBindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A token and a password of at least 8 characters are required"})
+			return
+		}
+
+		var invitation models.UserInvitation
+		if err := db.Where("token_hash = ? AND accepted_at IS NULL AND expires_at > ?", utils.HashToken(reqBody.Token), time.Now()).
+			First(&invitation).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found or expired"})
+			return
+		}
+
+		// The email may have been registered since the invitation was sent.
+		var count int64
+		if err := db.Model(&models.User{}).Where("email = ?", invitation.Email).Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing users"})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "A user with this email already exists"})
+			return
+		}
+
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(reqBody.Password), bcrypt.DefaultCost)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+			return
+		}
+
+		user := models.User{
+			CompanyID:    invitation.CompanyID,
+			Email:        invitation.Email,
+			Name:         strings.TrimSpace(reqBody.Name),
+			PasswordHash: string(hashedPassword),
+			Role:         invitation.Role,
+			Status:       models.UserActive,
+		}
+		errAccepted := errors.New("invitation already accepted")
+		err = db.Transaction(func(tx *gorm.DB) error {
+			res := tx.Model(&invitation).Where("accepted_at IS NULL").Update("accepted_at", time.Now())
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return errAccepted
+			}
+			// The email may belong to a user removed earlier.
+			if err := tx.Unscoped().Where("email = ? AND deleted_at IS NOT NULL", user.Email).
+				Delete(&models.User{}).Error; err != nil {
+				return err
+			}
+			return tx.Create(&user).Error
+		})
+		if errors.Is(err, errAccepted) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found or expired"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+			return
+		}
+		c.JSON(http.StatusCreated, user)
+	}
+}
@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"backend/signing"
+)
+
+// KeyRotationInterval is how often the JWT signing key is checked for
+// rotation. Each check also reloads the keys rotated by other instances.
+const KeyRotationInterval = time.Hour
+
+// StartKeyRotation rotates the JWT signing key every KeyRotationInterval when
+// it is due, in a background goroutine. signing.Init rotates on start up.
+func StartKeyRotation(db *gorm.DB) {
+	go func() {
+		ticker := time.NewTicker(KeyRotationInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := signing.Rotate(db, time.Now()); err != nil {
+				log.Println("Error rotating JWT signing key:", err)
+			}
+		}
+	}()
+}
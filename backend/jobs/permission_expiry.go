@@ -0,0 +1,69 @@
+// Package jobs holds the background tasks run alongside the API server.
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"backend/models"
+)
+
+// PermissionExpiryInterval is how often expired permission grants are swept.
+// Access checks compare expiry dates themselves, so the sweep only has to
+// bring the stored status and history up to date.
+const PermissionExpiryInterval = 15 * time.Minute
+
+// StartPermissionExpiry expires due permission grants now and then every
+// PermissionExpiryInterval, in a background goroutine.
+func StartPermissionExpiry(db *gorm.DB) {
+	go func() {
+		ticker := time.NewTicker(PermissionExpiryInterval)
+		defer ticker.Stop()
+		for {
+			if n, err := ExpirePermissionGrants(db, time.Now()); err != nil {
+				log.Println("Error expiring permission grants:", err)
+			} else if n > 0 {
+				log.Printf("Expired %d permission grants", n)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// ExpirePermissionGrants marks the permitted requests whose expiry date has
+// passed as expired and records the change in their history. It returns the
+// number of grants expired.
+func ExpirePermissionGrants(db *gorm.DB, now time.Time) (int, error) {
+	var expired []struct {
+		ID        uint
+		ExpiresAt *time.Time
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Raw(`
+            UPDATE permission_requests SET status = ?, updated_at = ?
+            WHERE status = ? AND expires_at <= ? AND deleted_at IS NULL
+            RETURNING id, expires_at`,
+			models.PermissionExpired, now, models.PermissionPermitted, now).
+			Scan(&expired).Error; err != nil {
+			return err
+		}
+		for _, r := range expired {
+			if err := tx.Create(&models.PermissionRequestHistory{
+				PermissionRequestID: r.ID,
+				Action:              "expire",
+				FromStatus:          models.PermissionPermitted,
+				ToStatus:            models.PermissionExpired,
+				ExpiresAt:           r.ExpiresAt,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(expired), nil
+}
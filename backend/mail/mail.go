@@ -0,0 +1,96 @@
+// Package mail sends the emails of the API through a configurable Sender:
+// SMTP in production, or files or the log to try the flows locally without a
+// mail server.
+package mail
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers messages.
+type Sender interface {
+	Send(msg Message) error
+}
+
+var (
+	mu     sync.RWMutex
+	sender Sender = LogSender{}
+)
+
+// Init chooses the sender from the environment:
+//
+//	MAIL_DRIVER   smtp, file or log (default outside production)
+//	MAIL_FROM     sender address
+//	SMTP_HOST, SMTP_PORT (default 587), SMTP_USERNAME, SMTP_PASSWORD
+//	MAIL_DIR      directory of the file driver (default "outbox")
+//
+// Emails carry password reset and invitation links, so in production the log
+// driver is refused and the driver must be set explicitly.
+func Init() error {
+	from := os.Getenv("MAIL_FROM")
+	production := os.Getenv("ENV") == "production"
+	var s Sender
+	switch driver := os.Getenv("MAIL_DRIVER"); driver {
+	case "", "log":
+		if production {
+			return fmt.Errorf("MAIL_DRIVER must be smtp or file in production, the log driver would write account links to the logs")
+		}
+		s = LogSender{}
+	case "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "outbox"
+		}
+		s = FileSender{Dir: dir, From: from}
+	case "smtp":
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		smtpSender := SMTPSender{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}
+		if smtpSender.Host == "" || smtpSender.From == "" {
+			return fmt.Errorf("SMTP_HOST and MAIL_FROM are required for the smtp mail driver")
+		}
+		s = smtpSender
+	default:
+		return fmt.Errorf("unknown MAIL_DRIVER %q", driver)
+	}
+	if production {
+		if _, ok := s.(SMTPSender); !ok {
+			log.Println("Warning: emails are not delivered, set MAIL_DRIVER=smtp")
+		}
+	}
+	SetSender(s)
+	return nil
+}
+
+// SetSender replaces the sender used by Send.
+func SetSender(s Sender) {
+	mu.Lock()
+	defer mu.Unlock()
+	sender = s
+}
+
+// Send delivers the message with the configured sender.
+func Send(msg Message) error {
+	mu.RLock()
+	s := sender
+	mu.RUnlock()
+	return s.Send(msg)
+}
@@ -0,0 +1,93 @@
+package mail
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SMTPSender delivers messages through an SMTP server, authenticating with
+// PLAIN auth when a username is set. The connection is upgraded with
+// STARTTLS when the server offers it.
+type SMTPSender struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send implements Sender.
+func (s SMTPSender) Send(msg Message) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	return smtp.SendMail(net.JoinHostPort(s.Host, s.Port), auth, s.From, []string{msg.To}, format(s.From, msg))
+}
+
+// FileSender writes every message to its own .eml file in Dir.
+type FileSender struct {
+	Dir  string
+	From string
+}
+
+// Send implements Sender.
+func (s FileSender) Send(msg Message) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102T150405.000000000"), sanitize(msg.To))
+	path := filepath.Join(s.Dir, name)
+	if err := os.WriteFile(path, format(s.From, msg), 0o600); err != nil {
+		return err
+	}
+	log.Printf("Wrote email to %s: %s", msg.To, path)
+	return nil
+}
+
+// LogSender writes messages to the log instead of delivering them.
+type LogSender struct{}
+
+// Send implements Sender.
+func (LogSender) Send(msg Message) error {
+	log.Printf("Email to %s\nSubject: %s\n\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// format returns the message with its headers, as sent over SMTP.
+func format(from string, msg Message) []byte {
+	var b strings.Builder
+	if from != "" {
+		fmt.Fprintf(&b, "From: %s\r\n", from)
+	}
+	fmt.Fprintf(&b, "To: %s\r\n", stripNewlines(msg.To))
+	// Subjects such as company names may be non-ASCII.
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", stripNewlines(msg.Subject)))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// stripNewlines keeps header values on one line, so they cannot add headers.
+func stripNewlines(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// sanitize turns an address into a safe part of a file name.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '@' {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -6,8 +6,11 @@ import (
 	"os"
 
 	"backend/config"
+	"backend/jobs"
+	"backend/mail"
 	"backend/middleware"
 	"backend/routes"
+	"backend/signing"
 )
 
 func main() {
@@ -22,8 +25,21 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
-	// Initialize the secret key for JWT.
-	middleware.InitSecret()
+	// Load the JWT signing keys and initialize the session and API key lookup.
+	if err := signing.Init(db); err != nil {
+		log.Fatalf("Failed to load JWT signing keys: %v", err)
+	}
+	middleware.InitAuth(db)
+
+	// Choose how emails are sent.
+	if err := mail.Init(); err != nil {
+		log.Fatalf("Failed to configure mail: %v", err)
+	}
+
+	// Expire permission grants in the background.
+	jobs.StartPermissionExpiry(db)
+	// Rotate the JWT signing key when due.
+	jobs.StartKeyRotation(db)
 
 	// Set up all routes.
 	r := routes.SetupRoutes(db)
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/models"
+	"backend/utils"
+)
+
+// apiKeyUsageInterval is how often the last use of an API key is recorded at most.
+const apiKeyUsageInterval = time.Minute
+
+// authenticateAPIKey resolves an API key to its company and stores the
+// company, the key and its scopes in the context. It reports false when the
+// key is unknown, revoked or expired.
+func authenticateAPIKey(c *gin.Context, key string) bool {
+	if authDB == nil {
+		return false
+	}
+	// Keys stop working with their company.
+	var apiKey models.APIKey
+	if err := authDB.
+		Joins("JOIN companies ON companies.id = api_keys.company_id AND companies.deleted_at IS NULL AND companies.status = 'active'").
+		Where("api_keys.key_hash = ?", utils.HashToken(key)).
+		First(&apiKey).Error; err != nil {
+		return false
+	}
+	now := time.Now()
+	if !apiKey.Usable(now) {
+		return false
+	}
+
+	// Actions taken with the key are recorded under its prefix.
+	c.Set("companyID", apiKey.CompanyID)
+	c.Set("email", "api-key:"+apiKey.Prefix)
+	c.Set("apiKeyID", apiKey.ID)
+	c.Set("apiKeyScopes", apiKey.ScopeList())
+
+	if err := authDB.Model(&models.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", apiKey.ID, now.Add(-apiKeyUsageInterval)).
+		Update("last_used_at", now).Error; err != nil {
+		log.Println("Error recording API key use:", err)
+	}
+	return true
+}
@@ -2,38 +2,54 @@ package middleware
 
 import (
 	"net/http"
-	"os"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
-)
-
-var secretKey []byte
 
-func InitSecret() {
-	secretKey = []byte(os.Getenv("JWT_SECRET"))
-}
+	"backend/models"
+	"backend/signing"
+)
 
+// AuthMiddleware authenticates the request with a JWT, sent as a bearer token
+// or in the NextAuth session cookie, or with an API key sent as a bearer token.
+// It stores companyID, email and, for JWTs, userID, role and sessionID in the context.
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Get the JWT token from cookie.
-		cookie, err := c.Request.Cookie("next-auth.session-token")
-		if err != nil || cookie.Value == "" {
+		// Get the token from the Authorization header, or else the JWT from the NextAuth cookie.
+		tokenString := bearerToken(c)
+		fromCookie := false
+		if tokenString == "" {
+			if cookie, err := c.Request.Cookie("next-auth.session-token"); err == nil {
+				tokenString = cookie.Value
+				fromCookie = true
+			}
+		}
+		if tokenString == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token is required"})
 			c.Abort()
 			return
 		}
 
-		tokenString := cookie.Value
-
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Check the signing method.
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
+		if strings.HasPrefix(tokenString, models.APIKeyPrefix) {
+			if !authenticateAPIKey(c, tokenString) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+				c.Abort()
+				return
 			}
-			return secretKey, nil
-		})
+			c.Next()
+			return
+		}
+
+		// The cookie is signed by NextAuth, bearer tokens by the API's signing keys.
+		var token *jwt.Token
+		var err error
+		if fromCookie {
+			token, err = signing.ParseSessionCookie(tokenString)
+		} else {
+			token, err = signing.Parse(tokenString)
+		}
 
 		if err != nil || !token.Valid {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
@@ -43,6 +59,23 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		// Retrieve user information from the token and store it into context.
 		if claims, ok := token.Claims.(jwt.MapClaims); ok {
+			// Tokens issued before sessions existed carry no session and must be renewed by logging in again.
+			role, _ := claims["role"].(string)
+			userID, _ := claims["userID"].(float64)
+			sessionID, _ := claims["sid"].(float64)
+			if role == "" || userID == 0 || sessionID == 0 {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+				c.Abort()
+				return
+			}
+			if !sessionActive(uint(sessionID)) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has ended"})
+				c.Abort()
+				return
+			}
+			c.Set("role", role)
+			c.Set("userID", uint(userID))
+			c.Set("sessionID", uint(sessionID))
 
 			// Store email from claims.
 			if email, exists := claims["email"].(string); exists {
@@ -65,3 +98,12 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// bearerToken returns the token of an "Authorization: Bearer <token>" header, or "".
+func bearerToken(c *gin.Context) string {
+	scheme, token, found := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/models"
+)
+
+// Permission areas. Every role may read every area; areaWriters lists the
+// roles that may change it. API keys may only use the areas of their scopes.
+const (
+	AreaCatalog    = "catalog"    // products, price breaks, coupons, buyer discounts and price lists
+	AreaInventory  = "inventory"  // warehouses, stock levels and transfers
+	AreaPurchasing = "purchasing" // permission requests, orders and returns placed as a buyer
+	AreaSales      = "sales"      // decisions on incoming requests, orders and returns
+	AreaFulfilment = "fulfilment" // shipments and received returns
+	AreaFinance    = "finance"    // exchange rates and cost reports
+	AreaCompany    = "company"    // company settings, users and invitations
+)
+
+var areaWriters = map[string][]string{
+	AreaCatalog:    {models.RoleOwner, models.RoleAdmin},
+	AreaInventory:  {models.RoleOwner, models.RoleAdmin, models.RoleWarehouse},
+	AreaPurchasing: {models.RoleOwner, models.RoleAdmin, models.RoleBuyer},
+	AreaSales:      {models.RoleOwner, models.RoleAdmin},
+	AreaFulfilment: {models.RoleOwner, models.RoleAdmin, models.RoleWarehouse},
+	AreaFinance:    {models.RoleOwner, models.RoleAdmin, models.RoleAccountant},
+	AreaCompany:    {models.RoleOwner, models.RoleAdmin},
+}
+
+// ValidArea reports whether area is a permission area.
+func ValidArea(area string) bool {
+	_, ok := areaWriters[area]
+	return ok
+}
+
+// CanWrite reports whether the role may change the area.
+func CanWrite(role, area string) bool {
+	for _, r := range areaWriters[area] {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// keyAllows reports whether an API key with the given scopes may read or
+// change the area. Keys never reach the company itself, its users or its API
+// keys; no scopes means every other area.
+func keyAllows(scopes []string, area string) bool {
+	if area == AreaCompany {
+		return false
+	}
+	if len(scopes) == 0 {
+		return true
+	}
+	for _, s := range scopes {
+		if s == area {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize rejects API keys whose scopes do not cover the area, whatever the
+// method, and lets users read while rejecting changes to the area by roles not
+// allowed to make them. It must run after AuthMiddleware.
+func Authorize(area string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var allowed bool
+		if scopes, ok := c.Get("apiKeyScopes"); ok {
+			allowed = keyAllows(scopes.([]string), area)
+		} else {
+			switch c.Request.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				allowed = true
+			default:
+				allowed = CanWrite(c.GetString("role"), area)
+			}
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Your role does not allow this action"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
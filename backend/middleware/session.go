@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"gorm.io/gorm"
+
+	"backend/models"
+)
+
+// authDB is where AuthMiddleware looks up sessions and API keys.
+var authDB *gorm.DB
+
+// InitAuth lets AuthMiddleware look sessions and API keys up in the database.
+func InitAuth(db *gorm.DB) {
+	authDB = db
+}
+
+// sessionActive reports whether the session has not been revoked or expired
+// and its user and company are still active. Deactivating a company or
+// disabling a user thus ends their sessions at once.
+func sessionActive(sessionID uint) bool {
+	if authDB == nil {
+		return false
+	}
+	var count int64
+	err := authDB.Model(&models.Session{}).
+		Joins("JOIN users ON users.id = sessions.user_id AND users.deleted_at IS NULL AND users.status = ?", models.UserActive).
+		Joins("JOIN companies ON companies.id = sessions.company_id AND companies.deleted_at IS NULL AND companies.status = 'active'").
+		Where("sessions.id = ? AND sessions.revoked_at IS NULL AND sessions.expires_at > NOW()", sessionID).
+		Count(&count).Error
+	return err == nil && count > 0
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/models"
+)
+
+// RequireVerifiedEmail rejects requests of companies that have not confirmed
+// their email yet. It runs after AuthMiddleware.
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if authDB == nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check email verification"})
+			c.Abort()
+			return
+		}
+		var count int64
+		if err := authDB.Model(&models.Companies{}).
+			Where("id = ? AND email_verified_at IS NOT NULL", c.GetUint("companyID")).
+			Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check email verification"})
+			c.Abort()
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Confirm your company's email address first"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyPrefix starts every API key, so that keys are recognisable in
+// configuration files and told apart from JWTs.
+const APIKeyPrefix = "imk_"
+
+// APIKey lets an integration call the API on behalf of a company with an
+// "Authorization: Bearer <key>" header. Only a hash of the key is stored;
+// Prefix keeps its first characters so that users can tell keys apart.
+type APIKey struct {
+	gorm.Model
+	CompanyID uint   `gorm:"not null;index" json:"company_id"`
+	Name      string `gorm:"type:varchar(100);not null" json:"name"`
+	Prefix    string `gorm:"type:varchar(16);not null" json:"prefix"`
+	KeyHash   string `gorm:"type:varchar(64);unique;not null" json:"-"`
+	// Scopes lists, comma separated, the permission areas the key may change.
+	// Empty allows every area except the company's own settings and users.
+	Scopes          string     `gorm:"type:varchar(255)" json:"scopes"`
+	CreatedByUserID uint       `json:"created_by_user_id"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt      *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt       *time.Time `json:"revoked_at,omitempty"`
+}
+
+// ScopeList returns the areas in Scopes.
+func (k *APIKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(k.Scopes, ",")
+}
+
+// Usable reports whether the key may be used at the given time.
+func (k *APIKey) Usable(at time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || at.Before(*k.ExpiresAt))
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// Checkout groups the per-seller orders created from a single cart submission.
+type Checkout struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CompanyID uint      `gorm:"not null;index" json:"company_id"` // buyer
+	Total     Money     `json:"total"`
+	Currency  string    `gorm:"type:varchar(3);default:'JPY';not null" json:"currency"`
+	CreatedAt time.Time `json:"created_at"`
+	Orders    []Order   `json:"orders"`
+}
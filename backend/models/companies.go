@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -12,4 +14,11 @@ type Companies struct {
 	Email        string `gorm:"type:varchar(100);unique;not null" json:"email"`
 	PasswordHash string `gorm:"type:varchar(255);not null" json:"-"`
 	Status       string `gorm:"type:varchar(50);default:'active';not null" json:"status"`
+	// RegistrationNumber is the qualified invoice issuer number ("T" followed by 13 digits).
+	RegistrationNumber string `gorm:"type:varchar(14)" json:"registration_number"`
+	// DefaultCurrency is the currency new products are priced in and purchases are reported in.
+	DefaultCurrency string `gorm:"type:varchar(3);default:'JPY';not null" json:"default_currency"`
+	// EmailVerifiedAt is when the company's email was confirmed. Until then the
+	// company cannot send permission requests or place orders.
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
 }
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Coupon types.
+const (
+	CouponPercent = "percent" // Percent off every line of the seller's order
+	CouponFixed   = "fixed"   // Amount off the seller's order, spread over its lines
+)
+
+// Kinds of discount applied to an order item.
+const (
+	DiscountPriceList  = "price_list"
+	DiscountPriceBreak = "price_break"
+	DiscountBuyer      = "buyer_discount"
+	DiscountCoupon     = "coupon"
+)
+
+// PriceBreak sets the unit price of a product from a minimum ordered quantity.
+// The break with the highest MinQuantity not above the ordered quantity applies.
+type PriceBreak struct {
+	gorm.Model
+	ProductID   uint  `gorm:"not null;index" json:"product_id"`
+	MinQuantity uint  `gorm:"not null" json:"min_quantity"`
+	UnitPrice   Money `gorm:"not null" json:"unit_price"` // in the product's currency
+}
+
+// Coupon is a seller's discount code redeemable by any buyer within its validity window.
+type Coupon struct {
+	gorm.Model
+	SellerID   uint       `gorm:"not null;uniqueIndex:idx_coupons_seller_code,where:deleted_at IS NULL" json:"seller_id"`
+	Code       string     `gorm:"type:varchar(50);not null;uniqueIndex:idx_coupons_seller_code,where:deleted_at IS NULL" json:"code"`
+	Type       string     `gorm:"type:varchar(20);not null" json:"type"`
+	Percent    uint       `gorm:"default:0;not null" json:"percent"` // percent coupons
+	Amount     Money      `gorm:"default:0;not null" json:"amount"`  // fixed coupons, in Currency
+	Currency   string     `gorm:"type:varchar(3);default:'JPY';not null" json:"currency"`
+	ValidFrom  *time.Time `json:"valid_from,omitempty"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+	MaxUses    uint       `gorm:"default:0;not null" json:"max_uses"` // 0 means unlimited
+	UsedCount  uint       `gorm:"default:0;not null" json:"used_count"`
+	Active     bool       `gorm:"default:true;not null" json:"active"`
+}
+
+// BuyerDiscount is a standing percentage a seller grants one buyer on every order.
+type BuyerDiscount struct {
+	gorm.Model
+	SellerID uint `gorm:"not null;uniqueIndex:idx_buyer_discounts_seller_buyer,where:deleted_at IS NULL" json:"seller_id"`
+	BuyerID  uint `gorm:"not null;uniqueIndex:idx_buyer_discounts_seller_buyer,where:deleted_at IS NULL" json:"buyer_id"`
+	Percent  uint `gorm:"not null" json:"percent"`
+}
+
+// OrderItemDiscount is one discount applied to an order item when the order was placed.
+type OrderItemDiscount struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	OrderItemID uint   `gorm:"not null;index" json:"order_item_id"`
+	Kind        string `gorm:"type:varchar(20);not null" json:"kind"`
+	PriceListID *uint  `json:"price_list_id,omitempty"`
+	CouponID    *uint  `json:"coupon_id,omitempty"`
+	Code        string `gorm:"type:varchar(50)" json:"code,omitempty"`
+	Percent     uint   `gorm:"default:0;not null" json:"percent,omitempty"`
+	Amount      Money  `gorm:"not null" json:"amount"` // reduction of the line total
+}
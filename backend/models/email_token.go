@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Purposes of an EmailToken.
+const (
+	TokenVerifyEmail   = "verify_email"
+	TokenResetPassword = "reset_password"
+)
+
+// EmailToken is a single-use token sent by email, to confirm the company's
+// email or to reset a user's password. Only a hash of the token is stored.
+type EmailToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	CompanyID uint       `gorm:"not null;index" json:"company_id"`
+	Purpose   string     `gorm:"type:varchar(20);not null" json:"purpose"`
+	Email     string     `gorm:"type:varchar(100);not null" json:"email"`
+	TokenHash string     `gorm:"type:varchar(64);unique;not null" json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"errors"
+	"math/big"
+	"time"
+)
+
+// ExchangeRate is one entry of the locally maintained exchange-rate table:
+// one unit of BaseCurrency buys Rate units of QuoteCurrency. Rates are never
+// updated in place; the most recent entry of a pair is the current rate.
+type ExchangeRate struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	BaseCurrency  string    `gorm:"type:varchar(3);not null;index:idx_exchange_rates_pair" json:"base_currency"`
+	QuoteCurrency string    `gorm:"type:varchar(3);not null;index:idx_exchange_rates_pair" json:"quote_currency"`
+	Rate          string    `gorm:"type:numeric(18,8);not null" json:"rate"`
+	CreatedByID   uint      `json:"created_by_id"`
+	CreatedBy     string    `gorm:"type:varchar(100)" json:"created_by"` // email
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ExchangeRateScale is the number of decimals kept for exchange rates.
+const ExchangeRateScale = 8
+
+// ErrInvalidRate is returned when an exchange rate is not a positive decimal.
+var ErrInvalidRate = errors.New("invalid exchange rate")
+
+// ParseRate parses a positive decimal exchange rate.
+func ParseRate(s string) (*big.Rat, error) {
+	rate, ok := new(big.Rat).SetString(s)
+	if !ok || rate.Sign() <= 0 {
+		return nil, ErrInvalidRate
+	}
+	return rate, nil
+}
+
+// Convert multiplies the amount by rate and rounds half away from zero to the
+// minor unit of the target currency.
+func (m Money) Convert(rate string, currency string) (Money, error) {
+	r, err := ParseRate(rate)
+	if err != nil {
+		return 0, err
+	}
+	if r.Cmp(big.NewRat(1, 1)) == 0 {
+		return m, nil
+	}
+
+	step := int64(1)
+	if decimals, ok := CurrencyDecimals[currency]; ok {
+		for i := decimals; i < MoneyScale; i++ {
+			step *= 10
+		}
+	}
+
+	// Work in steps of the target minor unit so the rounding happens once.
+	v := new(big.Rat).Mul(new(big.Rat).SetInt64(int64(m)), r)
+	v.Quo(v, new(big.Rat).SetInt64(step))
+	num, den := v.Num(), v.Denom()
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(int64(num.Sign())))
+	}
+	return Money(q.Int64() * step), nil
+}
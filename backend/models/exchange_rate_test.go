@@ -0,0 +1,62 @@
+package models
+
+import "testing"
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in      string
+		wantErr bool
+	}{
+		{"149.25", false},
+		{"0.00670000", false},
+		{"1", false},
+		{"0", true},
+		{"-1.5", true},
+		{"", true},
+		{"abc", true},
+	}
+	for _, tt := range tests {
+		if _, err := ParseRate(tt.in); (err != nil) != tt.wantErr {
+			t.Errorf("ParseRate(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMoneyConvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   Money
+		rate     string
+		currency string
+		want     Money
+	}{
+		{"yen to dollars", NewMoney(1000), "0.00670000", "USD", 670},
+		{"dollars to whole yen", 12345, "149.25", "JPY", NewMoney(18425)}, // 18424.9125
+		{"half a yen rounds up", 100, "0.5", "JPY", 100},                  // 0.5
+		{"negative half a yen rounds down", -100, "0.5", "JPY", -100},     // -0.5
+		{"half a cent rounds up", 1, "0.5", "USD", 1},                     // 0.005
+		{"below half a cent", 111, "0.00449", "EUR", 0},                   // 0.0049839
+		{"euros to dollars", 333, "0.33333333", "USD", 111},               // 1.1099999889
+		{"rate of one keeps the amount", 12345, "1", "JPY", 12345},
+		{"unknown currency keeps cents", 12345, "1.5", "XXX", 18518}, // 185.175
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.amount.Convert(tt.rate, tt.currency)
+			if err != nil {
+				t.Fatalf("Convert: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("%s.Convert(%s, %s) = %s, want %s", tt.amount, tt.rate, tt.currency, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyConvertInvalidRate(t *testing.T) {
+	for _, rate := range []string{"", "0", "-2", "abc"} {
+		if _, err := NewMoney(1).Convert(rate, "USD"); err == nil {
+			t.Errorf("Convert(%q) succeeded, want an error", rate)
+		}
+	}
+}
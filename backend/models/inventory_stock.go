@@ -4,11 +4,14 @@ import (
 	"gorm.io/gorm"
 )
 
+// InventoryStock is the stock of a product in one warehouse.
+// A product has at most one live row per warehouse.
 type InventoryStock struct {
 	gorm.Model
-	ProductID       uint      `gorm:"not null" json:"product_id"`
-	Product         Products  `json:"product,omitempty"`
-	WarehouseID     uint      `gorm:"not null" json:"warehouse_id"`
-	Warehouse       Warehouse `json:"warehouse,omitempty"`
-	QuantityInStock uint      `gorm:"default:0; not null" json:"quantity_in_stock"`
+	ProductID        uint      `gorm:"not null;uniqueIndex:idx_inventory_stocks_product_warehouse,where:deleted_at IS NULL" json:"product_id"`
+	Product          Products  `json:"product,omitempty"`
+	WarehouseID      uint      `gorm:"not null;uniqueIndex:idx_inventory_stocks_product_warehouse,where:deleted_at IS NULL" json:"warehouse_id"`
+	Warehouse        Warehouse `json:"warehouse,omitempty"`
+	QuantityInStock  uint      `gorm:"default:0; not null" json:"quantity_in_stock"`
+	QuantityReserved uint      `gorm:"default:0; not null" json:"quantity_reserved"` // held by pending orders
 }
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// Invoice is the invoice issued for a completed order. Numbers are sequential
+// per seller and, once allocated, are never reused.
+type Invoice struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	OrderID   uint      `gorm:"not null;uniqueIndex" json:"order_id"`
+	SellerID  uint      `gorm:"not null;uniqueIndex:idx_invoices_seller_number" json:"seller_id"`
+	Number    uint      `gorm:"not null;uniqueIndex:idx_invoices_seller_number" json:"number"`
+	IssuedAt  time.Time `gorm:"not null" json:"issued_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// InvoiceSequence holds the last invoice number allocated for a seller.
+type InvoiceSequence struct {
+	SellerID   uint `gorm:"primaryKey;autoIncrement:false" json:"seller_id"`
+	LastNumber uint `gorm:"not null;default:0" json:"last_number"`
+}
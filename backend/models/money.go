@@ -0,0 +1,172 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the ISO 4217 code of amounts that carry no explicit currency.
+const DefaultCurrency = "JPY"
+
+// CurrencyDecimals is the number of decimals of the minor unit of each supported currency.
+var CurrencyDecimals = map[string]int{
+	"JPY": 0,
+	"USD": 2,
+	"EUR": 2,
+}
+
+// MoneyScale is the number of decimals stored for every amount, whatever its currency.
+const MoneyScale = 2
+
+// moneyFactor is 10^MoneyScale.
+const moneyFactor = 100
+
+// Money is an exact amount in hundredths of a currency unit (e.g. 1234.50 is
+// stored as 123450). It is stored in numeric(14,2) columns and serialised to
+// JSON as a decimal string so clients never see binary rounding artifacts.
+type Money int64
+
+// ErrInvalidMoney is returned when parsing a malformed amount.
+var ErrInvalidMoney = errors.New("invalid money amount")
+
+// NewMoney returns the amount of whole currency units.
+func NewMoney(units int64) Money {
+	return Money(units * moneyFactor)
+}
+
+// ParseMoney parses a decimal string such as "1234.5" or "-0.25".
+// Digits beyond MoneyScale are rounded half away from zero.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, ErrInvalidMoney
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	for _, part := range []string{whole, frac} {
+		for _, r := range part {
+			if r < '0' || r > '9' {
+				return 0, ErrInvalidMoney
+			}
+		}
+	}
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || units > (1<<62)/moneyFactor {
+		return 0, ErrInvalidMoney
+	}
+	padded := frac + strings.Repeat("0", MoneyScale)
+	cents, _ := strconv.ParseInt(padded[:MoneyScale], 10, 64)
+	m := Money(units*moneyFactor + cents)
+	if len(frac) > MoneyScale && frac[MoneyScale] >= '5' {
+		m++
+	}
+	if negative {
+		m = -m
+	}
+	return m, nil
+}
+
+// String formats the amount with exactly MoneyScale decimals.
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign, v = "-", -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/moneyFactor, v%moneyFactor)
+}
+
+// Mul returns the amount multiplied by a quantity.
+func (m Money) Mul(quantity uint) Money {
+	return m * Money(quantity)
+}
+
+// Percent returns rate percent of the amount, rounded towards zero to the minor
+// unit of currency (whole yen for JPY).
+func (m Money) Percent(rate uint, currency string) Money {
+	return (m * Money(rate) / 100).Truncate(currency)
+}
+
+// Truncate drops the digits below the minor unit of currency.
+func (m Money) Truncate(currency string) Money {
+	decimals, ok := CurrencyDecimals[currency]
+	if !ok || decimals >= MoneyScale {
+		return m
+	}
+	step := Money(1)
+	for i := decimals; i < MoneyScale; i++ {
+		step *= 10
+	}
+	return m / step * step
+}
+
+// MarshalJSON encodes the amount as a decimal string.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(m.String())), nil
+}
+
+// UnmarshalJSON accepts a decimal string or a bare JSON number.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	// Numbers are parsed from their text, never through float64.
+	if strings.ContainsAny(s, "eE") {
+		return ErrInvalidMoney
+	}
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value stores the amount as an exact decimal string.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan reads a numeric column.
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case []byte:
+		parsed, err := ParseMoney(string(v))
+		*m = parsed
+		return err
+	case string:
+		parsed, err := ParseMoney(v)
+		*m = parsed
+		return err
+	case int64:
+		*m = NewMoney(v)
+		return nil
+	case float64:
+		// Only reached for columns not yet migrated to numeric.
+		parsed, err := ParseMoney(strconv.FormatFloat(v, 'f', MoneyScale, 64))
+		*m = parsed
+		return err
+	}
+	return fmt.Errorf("cannot scan %T into Money", src)
+}
+
+// GormDataType makes every Money column an exact decimal.
+func (Money) GormDataType() string {
+	return "numeric(14,2)"
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{"1234.50", 123450, false},
+		{"1234.5", 123450, false},
+		{"0.25", 25, false},
+		{".5", 50, false},
+		{"-0.25", -25, false},
+		{"+3", 300, false},
+		{"0.125", 13, false},
+		{"-0.125", -13, false},
+		{"0.124", 12, false},
+		{"", 0, true},
+		{".", 0, true},
+		{"1.2.3", 0, true},
+		{"12a", 0, true},
+		{"1e3", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, %v; want %d, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		in   Money
+		want string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{123450, "1234.50"},
+		{-25, "-0.25"},
+		{NewMoney(-3), "-3.00"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q, want %q", int64(tt.in), got, tt.want)
+		}
+	}
+}
+
+func TestMoneyPercent(t *testing.T) {
+	tests := []struct {
+		amount   Money
+		rate     uint
+		currency string
+		want     Money
+	}{
+		{NewMoney(1000), 10, "JPY", NewMoney(100)},
+		{NewMoney(999), 10, "JPY", NewMoney(99)},     // 99.9 yen truncated
+		{NewMoney(999), 10, "USD", 9990},             // 99.90 dollars kept
+		{1999, 15, "USD", 299},                       // 2.9985 truncated to 2.99
+		{NewMoney(1000), 100, "JPY", NewMoney(1000)}, // the whole amount
+		{NewMoney(-999), 10, "JPY", NewMoney(-99)},   // towards zero
+		{NewMoney(1000), 0, "EUR", 0},
+	}
+	for _, tt := range tests {
+		if got := tt.amount.Percent(tt.rate, tt.currency); got != tt.want {
+			t.Errorf("%s.Percent(%d, %s) = %s, want %s", tt.amount, tt.rate, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyTruncate(t *testing.T) {
+	tests := []struct {
+		amount   Money
+		currency string
+		want     Money
+	}{
+		{12399, "JPY", 12300},
+		{-12399, "JPY", -12300},
+		{12399, "USD", 12399},
+		{12399, "XXX", 12399}, // unknown currencies keep every digit
+	}
+	for _, tt := range tests {
+		if got := tt.amount.Truncate(tt.currency); got != tt.want {
+			t.Errorf("%s.Truncate(%s) = %s, want %s", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Price Money `json:"price"`
+	}{123450})
+	if err != nil || string(data) != `{"price":"1234.50"}` {
+		t.Fatalf("Marshal = %s, %v", data, err)
+	}
+
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{`"1234.50"`, 123450, false},
+		{`1234.5`, 123450, false},
+		{`0.1`, 10, false},
+		{`null`, 0, false},
+		{`1e3`, 0, true},
+		{`"abc"`, 0, true},
+	}
+	for _, tt := range tests {
+		var m Money
+		err := json.Unmarshal([]byte(tt.in), &m)
+		if (err != nil) != tt.wantErr || m != tt.want {
+			t.Errorf("Unmarshal(%s) = %s, %v; want %s, error %v", tt.in, m, err, tt.want, tt.wantErr)
+		}
+	}
+}
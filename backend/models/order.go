@@ -4,11 +4,47 @@ import (
 	"time"
 )
 
+// Order statuses. The allowed transitions between them are declared by the
+// order workflow in the handlers package.
+const (
+	OrderPending          = "Pending"
+	OrderProcessing       = "Processing"
+	OrderPartiallyShipped = "PartiallyShipped"
+	OrderShipped          = "Shipped"
+	OrderDelivered        = "Delivered"
+	OrderCompleted        = "Completed"
+	OrderCancelled        = "Cancelled"
+	OrderRejected         = "Rejected"
+)
+
+// Order is the purchase of one buyer from one seller in a single currency.
+// Amounts are in Currency, the seller's price currency. BuyerTotal and
+// SellerTotal are Total converted into the buyer's and the seller's own
+// currency at the rates fixed when the order was placed.
 type Order struct {
-	ID         uint        `gorm:"primaryKey" json:"id"`
-	CompanyID  uint        `json:"company_id"`
-	Total      float64     `json:"total"`
-	Status     string      `json:"status"`
-	Date       time.Time   `json:"date"`
-	OrderItems []OrderItem `json:"OrderItems"`
+	ID                 uint           `gorm:"primaryKey" json:"id"`
+	CompanyID          uint           `json:"company_id"`             // buyer
+	SellerID           uint           `gorm:"index" json:"seller_id"` // supplier of every item in the order
+	CheckoutID         *uint          `gorm:"index" json:"checkout_id,omitempty"`
+	Subtotal           Money          `gorm:"default:0;not null" json:"subtotal"`                     // excluding tax
+	TaxTotal           Money          `gorm:"default:0;not null" json:"tax_total"`                    // sum of TaxLines
+	Total              Money          `json:"total"`                                                  // including tax
+	Currency           string         `gorm:"type:varchar(3);default:'JPY';not null" json:"currency"` // seller's price currency
+	BuyerCurrency      string         `gorm:"type:varchar(3);default:'JPY';not null" json:"buyer_currency"`
+	ExchangeRate       string         `gorm:"type:numeric(18,8);default:1;not null" json:"exchange_rate"`
+	BuyerTotal         Money          `gorm:"default:0;not null" json:"buyer_total"`
+	SellerCurrency     string         `gorm:"type:varchar(3);default:'JPY';not null" json:"seller_currency"`
+	SellerExchangeRate string         `gorm:"type:numeric(18,8);default:1;not null" json:"seller_exchange_rate"`
+	SellerTotal        Money          `gorm:"default:0;not null" json:"seller_total"`
+	Status             string         `json:"status"`
+	Date               time.Time      `json:"date"`
+	OrderItems         []OrderItem    `json:"OrderItems"`
+	TaxLines           []OrderTaxLine `json:"tax_lines"`
+	CancelledAt        *time.Time     `json:"cancelled_at,omitempty"`
+	CancelledBy        string         `gorm:"type:varchar(100)" json:"cancelled_by,omitempty"` // email of the buyer who cancelled
+	CancellationReason string         `json:"cancellation_reason,omitempty"`
+	RejectedAt         *time.Time     `json:"rejected_at,omitempty"`
+	RejectedByID       *uint          `json:"rejected_by_id,omitempty"` // seller company that rejected
+	RejectedBy         string         `gorm:"type:varchar(100)" json:"rejected_by,omitempty"`
+	RejectionReason    string         `json:"rejection_reason,omitempty"`
 }
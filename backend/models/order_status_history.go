@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// OrderStatusHistory records a single status transition of an order.
+type OrderStatusHistory struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	OrderID        uint      `gorm:"not null;index" json:"order_id"`
+	Action         string    `gorm:"type:varchar(50);not null" json:"action"`
+	FromStatus     string    `gorm:"type:varchar(50)" json:"from_status"`
+	ToStatus       string    `gorm:"type:varchar(50);not null" json:"to_status"`
+	ActorCompanyID uint      `json:"actor_company_id"`
+	ActorEmail     string    `gorm:"type:varchar(100)" json:"actor_email"`
+	Comment        string    `json:"comment,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
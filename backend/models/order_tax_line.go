@@ -0,0 +1,11 @@
+package models
+
+// OrderTaxLine is the per-rate tax breakdown of an order. Tax is rounded once
+// per rate and order, as required for qualified invoices.
+type OrderTaxLine struct {
+	ID            uint  `gorm:"primaryKey" json:"id"`
+	OrderID       uint  `gorm:"not null;uniqueIndex:idx_order_tax_lines_order_rate" json:"order_id"`
+	TaxRate       uint  `gorm:"not null;uniqueIndex:idx_order_tax_lines_order_rate" json:"tax_rate"` // percent
+	TaxableAmount Money `gorm:"not null" json:"taxable_amount"`
+	TaxAmount     Money `gorm:"not null" json:"tax_amount"`
+}
@@ -4,10 +4,20 @@ import (
 	"gorm.io/gorm"
 )
 
+// OrderItem is one line of an order. ListPrice is the catalog price; Price is
+// the effective unit price after Discounts, and LineTotal the exact amount
+// charged for the line, excluding tax.
 type OrderItem struct {
 	gorm.Model
-	OrderID   uint    `gorm:"not null" json:"order_id"`
-	ProductID uint    `gorm:"not null" json:"product_id"`
-	Quantity  uint    `gorm:"not null" json:"quantity"`
-	Price     float64 `gorm:"not null" json:"price"` // price at time of order
+	OrderID         uint                `gorm:"not null" json:"order_id"`
+	ProductID       uint                `gorm:"not null" json:"product_id"`
+	Quantity        uint                `gorm:"not null" json:"quantity"`
+	ListPrice       Money               `gorm:"default:0;not null" json:"list_price"`
+	Price           Money               `gorm:"not null" json:"price"`                                      // price at time of order, excluding tax
+	LineTotal       Money               `gorm:"default:0;not null" json:"line_total"`                       // excluding tax
+	TaxRate         uint                `gorm:"default:0;not null" json:"tax_rate"`                         // percent, at time of order
+	ExchangeRate    string              `gorm:"type:numeric(18,8);default:1;not null" json:"exchange_rate"` // order to buyer currency, at time of order
+	BuyerPrice      Money               `gorm:"default:0;not null" json:"buyer_price"`                      // Price in the buyer's currency
+	QuantityShipped uint                `gorm:"default:0;not null" json:"quantity_shipped"`
+	Discounts       []OrderItemDiscount `json:"discounts"`
 }
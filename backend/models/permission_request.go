@@ -1,14 +1,77 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Permission request statuses. Only a permitted request that has not passed
+// its expiry date grants access to the seller's products.
+const (
+	PermissionPending   = "pending"
+	PermissionPermitted = "permitted"
+	PermissionRejected  = "rejected"
+	PermissionRevoked   = "revoked"   // permitted, then withdrawn by the seller
+	PermissionWithdrawn = "withdrawn" // pending, then withdrawn by the buyer
+	PermissionExpired   = "expired"
+)
 
 // PermissionRequest represents a request from a customer (requester)
-// to gain access to a seller's products.
+// to gain access to a seller's products. A permitted request without Scopes
+// shares the seller's whole catalog; otherwise only the products matching
+// at least one scope are shared.
 type PermissionRequest struct {
 	gorm.Model
-	SellerID       uint   `json:"seller_id"`    // the targeted seller company id
-	RequesterID    uint   `json:"requester_id"` // the sending (customer) company id
-	RequesterEmail string `json:"requester_email"`
-	RequesterPhone string `json:"requester_phone"`
-	Status         string `json:"status"` // "pending", "permitted", "rejected"
+	SellerID         uint                `json:"seller_id"`    // the targeted seller company id
+	RequesterID      uint                `json:"requester_id"` // the sending (customer) company id
+	RequesterEmail   string              `json:"requester_email"`
+	RequesterPhone   string              `json:"requester_phone"`
+	Status           string              `json:"status"`
+	ExpiresAt        *time.Time          `gorm:"index" json:"expires_at,omitempty"` // no expiry when nil
+	RevokedAt        *time.Time          `json:"revoked_at,omitempty"`
+	RevocationReason string              `json:"revocation_reason,omitempty"`
+	Scopes           []PermissionScope   `json:"scopes"`
+	Messages         []PermissionMessage `json:"messages"`
+}
+
+// Active reports whether the request grants access at the given time.
+func (r *PermissionRequest) Active(at time.Time) bool {
+	return r.Status == PermissionPermitted && (r.ExpiresAt == nil || at.Before(*r.ExpiresAt))
+}
+
+// PermissionScope shares one product, one product category or the products
+// stocked in one warehouse. Exactly one of ProductID, Category and WarehouseID is set.
+type PermissionScope struct {
+	ID                  uint   `gorm:"primaryKey" json:"id"`
+	PermissionRequestID uint   `gorm:"not null;index" json:"permission_request_id"`
+	ProductID           *uint  `json:"product_id,omitempty"`
+	Category            string `gorm:"type:varchar(50)" json:"category,omitempty"`
+	WarehouseID         *uint  `json:"warehouse_id,omitempty"`
+}
+
+// PermissionRequestHistory records a single status change of a permission request.
+// Changes made by the expiry task have no actor.
+type PermissionRequestHistory struct {
+	ID                  uint       `gorm:"primaryKey" json:"id"`
+	PermissionRequestID uint       `gorm:"not null;index" json:"permission_request_id"`
+	Action              string     `gorm:"type:varchar(50);not null" json:"action"`
+	FromStatus          string     `gorm:"type:varchar(50)" json:"from_status"`
+	ToStatus            string     `gorm:"type:varchar(50);not null" json:"to_status"`
+	ExpiresAt           *time.Time `json:"expires_at,omitempty"`
+	ActorCompanyID      uint       `json:"actor_company_id"`
+	ActorEmail          string     `gorm:"type:varchar(100)" json:"actor_email"`
+	Comment             string     `json:"comment,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+// PermissionMessage is one message of the conversation between the buyer and
+// the seller about a permission request.
+type PermissionMessage struct {
+	ID                  uint      `gorm:"primaryKey" json:"id"`
+	PermissionRequestID uint      `gorm:"not null;index" json:"permission_request_id"`
+	AuthorCompanyID     uint      `gorm:"not null" json:"author_company_id"`
+	AuthorEmail         string    `gorm:"type:varchar(100)" json:"author_email"`
+	Body                string    `gorm:"type:text;not null" json:"body"`
+	CreatedAt           time.Time `json:"created_at"`
 }
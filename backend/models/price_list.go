@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PriceList holds the prices a seller contracted with one permitted buyer.
+// Products listed in Items are sold at their own unit price; every other
+// product is sold at Percent off its list price. A list only applies while
+// its permission request is permitted and within its effective dates.
+type PriceList struct {
+	gorm.Model
+	SellerID            uint            `gorm:"not null;index" json:"seller_id"`
+	BuyerID             uint            `gorm:"not null;index" json:"buyer_id"`
+	PermissionRequestID uint            `gorm:"not null;index" json:"permission_request_id"`
+	Name                string          `gorm:"type:varchar(100);not null" json:"name"`
+	Percent             uint            `gorm:"default:0;not null" json:"percent"` // off list price for products without an item
+	EffectiveFrom       *time.Time      `json:"effective_from,omitempty"`
+	EffectiveTo         *time.Time      `json:"effective_to,omitempty"` // exclusive
+	Items               []PriceListItem `json:"items"`
+}
+
+// PriceListItem is the contracted unit price of one product, in the product's currency.
+type PriceListItem struct {
+	ID          uint  `gorm:"primaryKey" json:"id"`
+	PriceListID uint  `gorm:"not null;uniqueIndex:idx_price_list_items_product" json:"price_list_id"`
+	ProductID   uint  `gorm:"not null;uniqueIndex:idx_price_list_items_product" json:"product_id"`
+	UnitPrice   Money `gorm:"not null" json:"unit_price"`
+}
+
+// UnitPrice returns the contracted unit price of a product listed at listPrice in currency.
+func (l *PriceList) UnitPrice(productID uint, listPrice Money, currency string) Money {
+	for _, item := range l.Items {
+		if item.ProductID == productID {
+			return item.UnitPrice
+		}
+	}
+	return listPrice - listPrice.Percent(l.Percent, currency)
+}
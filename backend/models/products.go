@@ -4,13 +4,35 @@ import (
 	"gorm.io/gorm"
 )
 
+// Consumption tax categories. Reduced-rate items (food, newspapers) are taxed
+// at 8%, everything else at the standard 10%.
+const (
+	TaxCategoryStandard = "standard"
+	TaxCategoryReduced  = "reduced"
+)
+
+// TaxRates maps each tax category to its rate in percent.
+var TaxRates = map[string]uint{
+	TaxCategoryStandard: 10,
+	TaxCategoryReduced:  8,
+}
+
+// Product statuses. Only active products can be ordered.
+const (
+	ProductActive   = "active"
+	ProductInactive = "inactive"
+)
+
 type Products struct {
 	gorm.Model
 	ProductName string    `gorm:"type:varchar(100); not null" json:"product_name"` // removed unique constraint
 	Sku         string    `gorm:"type:varchar(50); unique" json:"sku"`
 	Description string    `json:"description,omitempty"`
+	Category    string    `gorm:"type:varchar(50); index" json:"category"` // free-form, used to scope catalog sharing
 	SupplierID  uint      `gorm:"not null" json:"supplier_id"`
 	Supplier    Companies `json:"supplier,omitempty"`
-	Price       float64   `gorm:"type:decimal(10,2); not null" json:"price"`
+	Price       Money     `gorm:"not null" json:"price"`
+	Currency    string    `gorm:"type:varchar(3); default:'JPY'; not null" json:"currency"`
 	Status      string    `gorm:"type:varchar(50); default:'active'; not null" json:"status"`
+	TaxCategory string    `gorm:"type:varchar(20); default:'standard'; not null" json:"tax_category"`
 }
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Return request statuses.
+const (
+	ReturnRequested = "requested"
+	ReturnApproved  = "approved"
+	ReturnRejected  = "rejected"
+	ReturnReceived  = "received"
+)
+
+// Dispositions the seller can choose when returned goods arrive.
+const (
+	DispositionRestock  = "restock"
+	DispositionWriteOff = "write_off"
+)
+
+// ReturnRequest is a buyer's request to send back items of a delivered order (RMA).
+// Once approved, RefundAmount (including RefundTax) is credited back to the buyer.
+type ReturnRequest struct {
+	gorm.Model
+	OrderID       uint         `gorm:"not null;index" json:"order_id"`
+	BuyerID       uint         `gorm:"not null;index" json:"buyer_id"`
+	SellerID      uint         `gorm:"not null;index" json:"seller_id"`
+	Status        string       `gorm:"type:varchar(20);default:'requested';not null" json:"status"`
+	Reason        string       `gorm:"not null" json:"reason"`
+	SellerComment string       `json:"seller_comment,omitempty"`
+	RefundAmount  Money        `gorm:"default:0;not null" json:"refund_amount"`
+	RefundTax     Money        `gorm:"default:0;not null" json:"refund_tax"`
+	Disposition   string       `gorm:"type:varchar(20)" json:"disposition,omitempty"`
+	WarehouseID   *uint        `json:"warehouse_id,omitempty"` // restock target
+	DecidedAt     *time.Time   `json:"decided_at,omitempty"`
+	ReceivedAt    *time.Time   `json:"received_at,omitempty"`
+	Lines         []ReturnLine `gorm:"foreignKey:ReturnID" json:"lines"`
+}
+
+// ReturnLine is a quantity of one order item included in a return.
+type ReturnLine struct {
+	gorm.Model
+	ReturnID    uint  `gorm:"not null;index" json:"return_id"`
+	OrderItemID uint  `gorm:"not null;index" json:"order_item_id"`
+	ProductID   uint  `gorm:"not null" json:"product_id"`
+	Quantity    uint  `gorm:"not null" json:"quantity"`
+	Price       Money `gorm:"not null" json:"price"`              // unit price charged on the order, excluding tax
+	TaxRate     uint  `gorm:"default:0;not null" json:"tax_rate"` // percent charged on the order
+	Amount      Money `gorm:"default:0;not null" json:"amount"`   // share of the order line total, excluding tax
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Session is a login of a user. Access tokens carry its id and stop working
+// as soon as it is revoked; the refresh token renews them until ExpiresAt and
+// is replaced on every use.
+type Session struct {
+	ID               uint   `gorm:"primaryKey" json:"id"`
+	UserID           uint   `gorm:"not null;index" json:"user_id"`
+	CompanyID        uint   `gorm:"not null;index" json:"company_id"`
+	RefreshTokenHash string `gorm:"type:varchar(64);unique;not null" json:"-"`
+	// PreviousTokenHash is the refresh token replaced last. Presenting it again
+	// means the token was copied, and the session is revoked.
+	PreviousTokenHash string     `gorm:"type:varchar(64);index" json:"-"`
+	UserAgent         string     `gorm:"type:varchar(255)" json:"user_agent"`
+	IPAddress         string     `gorm:"type:varchar(45)" json:"ip_address"`
+	ExpiresAt         time.Time  `json:"expires_at"`
+	LastUsedAt        time.Time  `json:"last_used_at"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty"`
+	RevocationReason  string     `gorm:"type:varchar(50)" json:"revocation_reason,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// Reasons a session was revoked.
+const (
+	SessionLogout          = "logout"
+	SessionLogoutAll       = "logout_all"
+	SessionPasswordChanged = "password_changed"
+	SessionAccountDeleted  = "account_deleted"
+	SessionUserChanged     = "user_changed" // role changed, disabled or removed
+	SessionTokenReuse      = "refresh_token_reuse"
+)
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Shipment is one parcel sent by the seller against an accepted order.
+// An order may be fulfilled by several shipments.
+type Shipment struct {
+	gorm.Model
+	OrderID        uint           `gorm:"not null;index" json:"order_id"`
+	SellerID       uint           `gorm:"not null" json:"seller_id"`
+	Carrier        string         `gorm:"type:varchar(100)" json:"carrier"`
+	TrackingNumber string         `gorm:"type:varchar(100)" json:"tracking_number"`
+	ShippedAt      time.Time      `json:"shipped_at"`
+	Lines          []ShipmentLine `json:"lines"`
+}
+
+// ShipmentLine is the quantity of an order item included in a shipment.
+type ShipmentLine struct {
+	gorm.Model
+	ShipmentID  uint `gorm:"not null;index" json:"shipment_id"`
+	OrderItemID uint `gorm:"not null;index" json:"order_item_id"`
+	ProductID   uint `gorm:"not null" json:"product_id"`
+	Quantity    uint `gorm:"not null" json:"quantity"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// SigningKey is a key pair signing the JWTs issued by the API. The newest key
+// that is not retired signs new tokens; retired keys still verify tokens for
+// a while, see the signing package. Keys are PEM encoded, the private key as
+// PKCS #8 and the public key as PKIX.
+type SigningKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Kid        string     `gorm:"type:varchar(32);unique;not null" json:"kid"`
+	Algorithm  string     `gorm:"type:varchar(10);not null" json:"algorithm"`
+	PrivateKey string     `gorm:"type:text;not null" json:"-"`
+	PublicKey  string     `gorm:"type:text;not null" json:"public_key"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RetiredAt  *time.Time `gorm:"index" json:"retired_at,omitempty"`
+}
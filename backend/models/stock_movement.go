@@ -0,0 +1,50 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Stock movement types recorded in the ledger.
+const (
+	MovementReceipt    = "receipt"
+	MovementShipment   = "shipment"
+	MovementAdjustment = "adjustment"
+	MovementTransfer   = "transfer"
+	MovementReturn     = "return"
+)
+
+// ErrStockMovementImmutable is returned when code tries to modify a ledger row.
+var ErrStockMovementImmutable = errors.New("stock movements are append-only")
+
+// StockMovement is a single append-only ledger entry for a product in a warehouse.
+// InventoryStock.QuantityInStock is the running sum of these rows.
+type StockMovement struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	ProductID    uint      `gorm:"not null;index:idx_stock_movements_product_warehouse" json:"product_id"`
+	WarehouseID  uint      `gorm:"not null;index:idx_stock_movements_product_warehouse" json:"warehouse_id"`
+	CompanyID    uint      `gorm:"not null;index" json:"company_id"` // owner of the stock
+	MovementType string    `gorm:"type:varchar(20);not null" json:"movement_type"`
+	ReasonCode   string    `gorm:"type:varchar(50)" json:"reason_code"`
+	Quantity     int64     `gorm:"not null" json:"quantity"`      // signed: positive adds stock, negative removes it
+	BalanceAfter int64     `gorm:"not null" json:"balance_after"` // warehouse balance after this movement
+	ActorID      uint      `json:"actor_id"`                      // company that performed the change
+	ActorEmail   string    `gorm:"type:varchar(100)" json:"actor_email"`
+	OrderID      *uint     `gorm:"index" json:"order_id,omitempty"`
+	TransferID   *uint     `gorm:"index" json:"transfer_id,omitempty"`
+	Note         string    `json:"note,omitempty"`
+	OccurredAt   time.Time `gorm:"not null;index" json:"occurred_at"`
+}
+
+// BeforeUpdate rejects any attempt to rewrite ledger history.
+func (m *StockMovement) BeforeUpdate(tx *gorm.DB) error {
+	return ErrStockMovementImmutable
+}
+
+// BeforeDelete rejects any attempt to remove ledger history.
+func (m *StockMovement) BeforeDelete(tx *gorm.DB) error {
+	return ErrStockMovementImmutable
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// Stock reservation statuses.
+const (
+	ReservationActive   = "active"
+	ReservationConsumed = "consumed"
+	ReservationReleased = "released"
+)
+
+// StockReservation holds stock in a warehouse for an order item until the
+// seller ships it (consumed) or the order is cancelled or rejected (released).
+type StockReservation struct {
+	gorm.Model
+	OrderID     uint   `gorm:"not null;index" json:"order_id"`
+	OrderItemID uint   `gorm:"not null;index" json:"order_item_id"`
+	ProductID   uint   `gorm:"not null" json:"product_id"`
+	WarehouseID uint   `gorm:"not null" json:"warehouse_id"`
+	CompanyID   uint   `gorm:"not null" json:"company_id"` // owner of the stock (seller)
+	Quantity    uint   `gorm:"not null" json:"quantity"`
+	Status      string `gorm:"type:varchar(20);default:'active';not null" json:"status"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Stock transfer statuses.
+const (
+	TransferDraft     = "draft"
+	TransferInTransit = "in_transit"
+	TransferReceived  = "received"
+)
+
+// StockTransfer moves goods between two warehouses of the same company.
+// Stock leaves the source when the transfer is dispatched and arrives at
+// the destination when it is received.
+type StockTransfer struct {
+	gorm.Model
+	CompanyID              uint                `gorm:"not null;index" json:"company_id"`
+	SourceWarehouseID      uint                `gorm:"not null" json:"source_warehouse_id"`
+	DestinationWarehouseID uint                `gorm:"not null" json:"destination_warehouse_id"`
+	Status                 string              `gorm:"type:varchar(20);default:'draft';not null" json:"status"`
+	Note                   string              `json:"note,omitempty"`
+	DispatchedAt           *time.Time          `json:"dispatched_at,omitempty"`
+	ReceivedAt             *time.Time          `json:"received_at,omitempty"`
+	Lines                  []StockTransferLine `gorm:"foreignKey:TransferID" json:"lines"`
+}
+
+// StockTransferLine is a product and quantity moved by a StockTransfer.
+type StockTransferLine struct {
+	gorm.Model
+	TransferID uint `gorm:"not null;index" json:"transfer_id"`
+	ProductID  uint `gorm:"not null" json:"product_id"`
+	Quantity   uint `gorm:"not null" json:"quantity"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// User roles. The owner and admins manage the company and its users; the
+// other roles may only change their own area, see middleware.Authorize.
+const (
+	RoleOwner      = "owner"
+	RoleAdmin      = "admin"
+	RoleBuyer      = "buyer"
+	RoleWarehouse  = "warehouse"
+	RoleAccountant = "accountant"
+	RoleReadOnly   = "read_only"
+)
+
+// Roles lists every user role.
+var Roles = []string{RoleOwner, RoleAdmin, RoleBuyer, RoleWarehouse, RoleAccountant, RoleReadOnly}
+
+// ValidRole reports whether role is one of Roles.
+func ValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// User statuses. Disabled users cannot log in.
+const (
+	UserActive   = "active"
+	UserDisabled = "disabled"
+)
+
+// User is a person logging in on behalf of a company. Every company has at
+// least one owner, created on registration with the company's email.
+type User struct {
+	gorm.Model
+	CompanyID    uint   `gorm:"not null;index" json:"company_id"`
+	Email        string `gorm:"type:varchar(100);unique;not null" json:"email"`
+	Name         string `gorm:"type:varchar(100)" json:"name"`
+	PasswordHash string `gorm:"type:varchar(255);not null" json:"-"`
+	Role         string `gorm:"type:varchar(20);not null" json:"role"`
+	Status       string `gorm:"type:varchar(20);default:'active';not null" json:"status"`
+}
+
+// UserInvitation lets the invited person create a user with the given role.
+// Only a hash of the token is stored; the token itself is handed out once.
+type UserInvitation struct {
+	gorm.Model
+	CompanyID       uint       `gorm:"not null;index" json:"company_id"`
+	Email           string     `gorm:"type:varchar(100);not null" json:"email"`
+	Role            string     `gorm:"type:varchar(20);not null" json:"role"`
+	TokenHash       string     `gorm:"type:varchar(64);unique;not null" json:"-"`
+	InvitedByUserID uint       `json:"invited_by_user_id"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	AcceptedAt      *time.Time `json:"accepted_at,omitempty"`
+}
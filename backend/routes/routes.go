@@ -36,15 +36,24 @@ func SetupRoutes(db *gorm.DB) *gin.Engine {
 	// Use the extracted CORS middleware.
 	r.Use(middleware.CORSMiddleware())
 
+	// Public keys verifying the API's JWTs.
+	r.GET("/.well-known/jwks.json", handlers.JWKSHandler())
+
 	authRoutes(r, db)
+	userRoutes(r, db)
 	productRoutes(r, db)
 	warehouseRoutes(r, db)
+	stockMovementRoutes(r, db)
 	permissionRequestRoutes(r, db)
 	purchaseRoutes(r, db)
 	orderRoutes(r, db)
+	returnRoutes(r, db)
 	salesRoutes(r, db)
 	settingsRoutes(r, db)
 	costRoutes(r, db)
+	exchangeRateRoutes(r, db)
+	discountRoutes(r, db)
+	priceListRoutes(r, db)
 
 	return r
 }
@@ -55,6 +64,17 @@ func authRoutes(r *gin.Engine, db *gorm.DB) {
 	{
 		auth.POST("/login/", handlers.LoginHandler(db))
 		auth.POST("/register/", handlers.RegisterHandler(db))
+		auth.POST("/invitations/accept/", handlers.AcceptInvitationHandler(db))
+		auth.POST("/email/verify/", handlers.VerifyEmailHandler(db))
+		auth.POST("/email/verify/resend/", middleware.AuthMiddleware(), handlers.ResendVerificationEmailHandler(db))
+		auth.POST("/password/forgot/", handlers.ForgotPasswordHandler(db))
+		auth.POST("/password/reset/", handlers.ResetPasswordHandler(db))
+		auth.POST("/token/refresh/", handlers.RefreshTokenHandler(db))
+		auth.POST("/token/revoke/", handlers.RevokeRefreshTokenHandler(db))
+		auth.POST("/logout/", middleware.AuthMiddleware(), handlers.LogoutHandler(db))
+		auth.POST("/logout/all/", middleware.AuthMiddleware(), handlers.LogoutAllHandler(db))
+		auth.GET("/user/sessions/", middleware.AuthMiddleware(), handlers.GetSessionsHandler(db))
+		auth.GET("/user/", middleware.AuthMiddleware(), handlers.GetCurrentUserHandler(db))
 		auth.PUT("/user/password/", middleware.AuthMiddleware(), handlers.ChangePasswordHandler(db))
 		auth.DELETE("/user/", middleware.AuthMiddleware(), handlers.DeleteAccountHandler(db))
 		auth.GET("/protected/", middleware.AuthMiddleware(), func(c *gin.Context) {
@@ -67,78 +87,209 @@ func authRoutes(r *gin.Engine, db *gorm.DB) {
 	}
 }
 
+// userRoutes groups and registers the management of the company's users and invitations.
+func userRoutes(r *gin.Engine, db *gorm.DB) {
+	users := r.Group("/api/users", middleware.AuthMiddleware(), middleware.Authorize(middleware.AreaCompany))
+	{
+		users.GET("/", handlers.GetUsersHandler(db))
+		users.PUT("/:id/", handlers.UpdateUserHandler(db))
+		users.DELETE("/:id/", handlers.DeleteUserHandler(db))
+		users.GET("/invitations/", handlers.GetInvitationsHandler(db))
+		users.POST("/invitations/", handlers.CreateInvitationHandler(db))
+		users.DELETE("/invitations/:invitationId/", handlers.DeleteInvitationHandler(db))
+	}
+}
+
 func productRoutes(r *gin.Engine, db *gorm.DB) {
-	products := r.Group("/api/products")
+	products := r.Group("/api/products", middleware.AuthMiddleware(), middleware.Authorize(middleware.AreaCatalog))
+	{
+		products.GET("/", handlers.GetProductsHandler(db))
+		products.GET("/:id/", handlers.GetProductHandler(db))
+		products.POST("/register/", handlers.RegisterProductHandler(db))
+		products.PUT("/:id/", handlers.UpdateProductHandler(db))
+		products.DELETE("/:id/", handlers.DeleteProductHandler(db))
+		products.GET("/:id/price-breaks/", handlers.GetPriceBreaksHandler(db))
+		products.PUT("/:id/price-breaks/", handlers.SetPriceBreaksHandler(db))
+	}
+
+	// Stock levels are kept by the warehouse staff.
+	productStock := r.Group("/api/products", middleware.AuthMiddleware(), middleware.Authorize(middleware.AreaInventory))
 	{
-		products.GET("/", middleware.AuthMiddleware(), handlers.GetProductsHandler(db))
-		products.GET("/:id/", middleware.AuthMiddleware(), handlers.GetProductHandler(db))
-		products.POST("/register/", middleware.AuthMiddleware(), handlers.RegisterProductHandler(db))
-		products.PUT("/:id/", middleware.AuthMiddleware(), handlers.UpdateProductHandler(db))
-		products.DELETE("/:id/", middleware.AuthMiddleware(), handlers.DeleteProductHandler(db))
+		productStock.GET("/:id/stock/", handlers.GetProductStockHandler(db))
+		productStock.PUT("/:id/stock/", handlers.SetProductStockHandler(db))
 	}
 }
 
 // warehouseRoutes groups and registers the warehouse endpoints.
 func warehouseRoutes(r *gin.Engine, db *gorm.DB) {
-	warehouses := r.Group("/api/warehouses")
+	warehouses := r.Group("/api/warehouses", middleware.AuthMiddleware(), middleware.Authorize(middleware.AreaInventory))
+	{
+		warehouses.GET("/", handlers.GetWarehousesHandler(db))
+		warehouses.GET("/:id/", handlers.GetWarehouseHandler(db))
+		warehouses.PUT("/:id/", handlers.UpdateWarehouseHandler(db))
+		warehouses.POST("/", handlers.AddWarehouseHandler(db))
+		warehouses.DELETE("/:id/", handlers.DeleteWarehouseHandler(db))
+
+		// Stock transfers between the company's own warehouses.
+		warehouses.GET("/transfers/", handlers.GetStockTransfersHandler(db))
+		warehouses.POST("/transfers/", handlers.CreateStockTransferHandler(db))
+		warehouses.GET("/transfers/:transferId/", handlers.GetStockTransferHandler(db))
+		warehouses.PUT("/transfers/:transferId/dispatch/", handlers.DispatchStockTransferHandler(db))
+		warehouses.PUT("/transfers/:transferId/receive/", handlers.ReceiveStockTransferHandler(db))
+		warehouses.DELETE("/transfers/:transferId/", handlers.DeleteStockTransferHandler(db))
+	}
+}
+
+// stockMovementRoutes groups and registers the stock ledger endpoints.
+func stockMovementRoutes(r *gin.Engine, db *gorm.DB) {
+	movements := r.Group("/api/stock-movements", middleware.AuthMiddleware(), middleware.Authorize(middleware.AreaInventory))
 	{
-		warehouses.GET("/", middleware.AuthMiddleware(), handlers.GetWarehousesHandler(db))
-		warehouses.GET("/:id/", middleware.AuthMiddleware(), handlers.GetWarehouseHandler(db))
-		warehouses.PUT("/:id/", middleware.AuthMiddleware(), handlers.UpdateWarehouseHandler(db))
-		warehouses.POST("/", middleware.AuthMiddleware(), handlers.AddWarehouseHandler(db))
-		warehouses.DELETE("/:id/", middleware.AuthMiddleware(), handlers.DeleteWarehouseHandler(db))
+		movements.GET("/", handlers.GetStockMovementsHandler(db))
+		movements.GET("/balance/", handlers.GetStockBalanceHandler(db))
 	}
 }
 
 // permissionRequestRoutes groups and registers the permission request endpoints.
+// Buyers send and follow their requests; the seller's decisions are sales actions.
 func permissionRequestRoutes(r *gin.Engine, db *gorm.DB) {
-	permissionRequests := r.Group("/api/requests")
+	permissionRequests := r.Group("/api/requests", middleware.AuthMiddleware(), middleware.Authorize(middleware.AreaPurchasing))
+	{
+		permissionRequests.GET("/", handlers.GetPermissionRequestsHandler(db))
+		permissionRequests.GET("/search/", handlers.SearchPermissionRequestsHandler(db))
+		permissionRequests.GET("/outgoing/", handlers.GetOutgoingPermissionRequestsHandler(db))
+		permissionRequests.POST("/", middleware.RequireVerifiedEmail(), handlers.SendPermissionRequestHandler(db))
+		permissionRequests.PUT("/:requestId/withdraw/", handlers.WithdrawPermissionRequestHandler(db))
+		permissionRequests.GET("/:requestId/history/", handlers.GetPermissionRequestHistoryHandler(db))
+		permissionRequests.GET("/:requestId/messages/", handlers.GetPermissionMessagesHandler(db))
+		permissionRequests.POST("/:requestId/messages/", handlers.PostPermissionMessageHandler(db))
+	}
+
+	decisions := r.Group("/api/requests", middleware.AuthMiddleware(), middleware.Authorize(middleware.AreaSales))
 	{
-		permissionRequests.GET("/", middleware.AuthMiddleware(), handlers.GetPermissionRequestsHandler(db))
-		permissionRequests.GET("/search/", middleware.AuthMiddleware(), handlers.SearchPermissionRequestsHandler(db))
-		permissionRequests.POST("/", middleware.AuthMiddleware(), handlers.SendPermissionRequestHandler(db))
-		permissionRequests.PUT("/:requestId/", middleware.AuthMiddleware(), handlers.UpdatePermissionRequestHandler(db))
+		decisions.PUT("/:requestId/", handlers.UpdatePermissionRequestHandler(db))
+		decisions.PUT("/:requestId/revoke/", handlers.RevokePermissionRequestHandler(db))
+		decisions.PUT("/:requestId/renew/", handlers.RenewPermissionRequestHandler(db))
 	}
 }
 
 func purchaseRoutes(r *gin.Engine, db *gorm.DB) {
-	purchaseProducts := r.Group("/api/purchase-products")
+	purchaseProducts := r.Group("/api/purchase-products", middleware.AuthMiddleware(), middleware.Authorize(middleware.AreaPurchasing))
 	{
-		purchaseProducts.GET("/", middleware.AuthMiddleware(), handlers.GetPurchaseProductsHandler(db))
+		purchaseProducts.GET("/", handlers.GetPurchaseProductsHandler(db))
 	}
 }
 
 func orderRoutes(r *gin.Engine, db *gorm.DB) {
-	orders := r.Group("/api/orders")
+	orders := r.Group("/api/orders", middleware.AuthMiddleware(), middleware.Authorize(middleware.AreaPurchasing))
+	{
+		orders.POST("/", middleware.RequireVerifiedEmail(), handlers.CreateOrderHandler(db))
+		orders.GET("/", handlers.GetOrdersHandler(db))
+		orders.GET("/:id/history/", handlers.GetOrderHistoryHandler(db))
+		orders.GET("/:id/shipments/", handlers.GetShipmentsHandler(db))
+		orders.POST("/:id/returns/", handlers.CreateReturnHandler(db))
+		orders.GET("/:id/invoice.pdf", handlers.GetInvoicePDFHandler(db))
+
+		// One route per order workflow action, e.g. PUT /api/orders/:id/cancel/.
+		for _, action := range handlers.OrderActions(false) {
+			orders.PUT("/:id/"+action+"/", handlers.OrderTransitionHandler(db, action))
+		}
+	}
+
+	sales := r.Group("/api/orders", middleware.AuthMiddleware(), middleware.Authorize(middleware.AreaSales))
+	{
+		// The seller's workflow actions, e.g. PUT /api/orders/:id/accept/.
+		for _, action := range handlers.OrderActions(true) {
+			sales.PUT("/:id/"+action+"/", handlers.OrderTransitionHandler(db, action))
+		}
+	}
+
+	fulfilment := r.Group("/api/orders", middleware.AuthMiddleware(), middleware.Authorize(middleware.AreaFulfilment))
+	{
+		fulfilment.POST("/:id/shipments/", handlers.CreateShipmentHandler(db))
+	}
+}
+
+// returnRoutes groups and registers the return (RMA) endpoints.
+func returnRoutes(r *gin.Engine, db *gorm.DB) {
+	returns := r.Group("/api/returns", middleware.AuthMiddleware(), middleware.Authorize(middleware.AreaSales))
+	{
+		returns.GET("/", handlers.GetReturnsHandler(db))
+		returns.GET("/:id/", handlers.GetReturnHandler(db))
+		returns.PUT("/:id/approve/", handlers.ApproveReturnHandler(db))
+		returns.PUT("/:id/reject/", handlers.RejectReturnHandler(db))
+	}
+
+	fulfilment := r.Group("/api/returns", middleware.AuthMiddleware(), middleware.Authorize(middleware.AreaFulfilment))
 	{
-		orders.POST("/", middleware.AuthMiddleware(), handlers.CreateOrderHandler(db))
-		orders.GET("/", middleware.AuthMiddleware(), handlers.GetOrdersHandler(db))
-		orders.PUT("/:id/accept/", middleware.AuthMiddleware(), handlers.AcceptOrderHandler(db))
-		orders.PUT("/:id/deliver/", middleware.AuthMiddleware(), handlers.DeliverOrderHandler(db))
-		orders.PUT("/:id/complete/", middleware.AuthMiddleware(), handlers.CompleteOrderHandler(db))
+		fulfilment.PUT("/:id/receive/", handlers.ReceiveReturnHandler(db))
 	}
 }
 
 func salesRoutes(r *gin.Engine, db *gorm.DB) {
-	sales := r.Group("/api/sales")
+	sales := r.Group("/api/sales", middleware.AuthMiddleware(), middleware.Authorize(middleware.AreaSales))
 	{
-		sales.GET("/", middleware.AuthMiddleware(), handlers.GetSalesHandler(db))
+		sales.GET("/", handlers.GetSalesHandler(db))
 	}
 }
 
 func settingsRoutes(r *gin.Engine, db *gorm.DB) {
-	settings := r.Group("/api/settings")
+	settings := r.Group("/api/settings", middleware.AuthMiddleware())
 	{
-		settings.GET("/", middleware.AuthMiddleware(), handlers.GetSettingsHandler(db))
-		settings.PUT("/update/", middleware.AuthMiddleware(), handlers.UpdateSettingsHandler(db))
-		settings.PUT("/password/", middleware.AuthMiddleware(), handlers.ChangeCompanyPasswordHandler(db))
+		settings.GET("/", middleware.Authorize(middleware.AreaCompany), handlers.GetSettingsHandler(db))
+		settings.PUT("/update/", middleware.Authorize(middleware.AreaCompany), handlers.UpdateSettingsHandler(db))
+		// Every user changes their own password.
+		settings.PUT("/password/", handlers.ChangeCompanyPasswordHandler(db))
+
+		// API keys for integrations, sent as "Authorization: Bearer <key>".
+		settings.GET("/api-keys/", middleware.Authorize(middleware.AreaCompany), handlers.GetAPIKeysHandler(db))
+		settings.POST("/api-keys/", middleware.Authorize(middleware.AreaCompany), handlers.CreateAPIKeyHandler(db))
+		settings.DELETE("/api-keys/:id/", middleware.Authorize(middleware.AreaCompany), handlers.RevokeAPIKeyHandler(db))
 	}
 }
 
 func costRoutes(r *gin.Engine, db *gorm.DB) {
-	costs := r.Group("/api/costs")
+	costs := r.Group("/api/costs", middleware.AuthMiddleware(), middleware.Authorize(middleware.AreaFinance))
 	{
 		// GET /api/costs returns the aggregated cost management data.
-		costs.GET("/", middleware.AuthMiddleware(), handlers.GetCostDataHandler(db))
+		costs.GET("/", handlers.GetCostDataHandler(db))
+	}
+}
+
+func exchangeRateRoutes(r *gin.Engine, db *gorm.DB) {
+	rates := r.Group("/api/exchange-rates", middleware.AuthMiddleware(), middleware.Authorize(middleware.AreaFinance))
+	{
+		rates.GET("/", handlers.GetExchangeRatesHandler(db))
+		rates.POST("/", handlers.CreateExchangeRateHandler(db))
+	}
+}
+
+// discountRoutes groups and registers the seller's coupon and buyer discount endpoints.
+// Quantity price breaks are registered with the product routes.
+func discountRoutes(r *gin.Engine, db *gorm.DB) {
+	coupons := r.Group("/api/coupons", middleware.AuthMiddleware(), middleware.Authorize(middleware.AreaCatalog))
+	{
+		coupons.GET("/", handlers.GetCouponsHandler(db))
+		coupons.POST("/", handlers.CreateCouponHandler(db))
+		coupons.PUT("/:id/", handlers.UpdateCouponHandler(db))
+		coupons.DELETE("/:id/", handlers.DeleteCouponHandler(db))
+	}
+
+	buyerDiscounts := r.Group("/api/buyer-discounts", middleware.AuthMiddleware(), middleware.Authorize(middleware.AreaCatalog))
+	{
+		buyerDiscounts.GET("/", handlers.GetBuyerDiscountsHandler(db))
+		buyerDiscounts.PUT("/", handlers.SetBuyerDiscountHandler(db))
+		buyerDiscounts.DELETE("/:id/", handlers.DeleteBuyerDiscountHandler(db))
+	}
+}
+
+// priceListRoutes groups and registers the buyer price list endpoints.
+func priceListRoutes(r *gin.Engine, db *gorm.DB) {
+	priceLists := r.Group("/api/price-lists", middleware.AuthMiddleware(), middleware.Authorize(middleware.AreaCatalog))
+	{
+		priceLists.GET("/", handlers.GetPriceListsHandler(db))
+		priceLists.GET("/:id/", handlers.GetPriceListHandler(db))
+		priceLists.POST("/", handlers.CreatePriceListHandler(db))
+		priceLists.PUT("/:id/", handlers.UpdatePriceListHandler(db))
+		priceLists.DELETE("/:id/", handlers.DeletePriceListHandler(db))
 	}
 }
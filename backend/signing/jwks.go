@@ -0,0 +1,60 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// JWK is a public key in the JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// Crv and X describe an Ed25519 key (RFC 8037).
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	// N and E describe an RSA key.
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicKeys returns the public keys that verify tokens: the current key and
+// the ones retired within the retention period.
+func PublicKeys() JWKSet {
+	now := time.Now()
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+
+	set := JWKSet{Keys: []JWK{}}
+	for _, k := range ring.keys {
+		if k.retiredAt != nil && now.Sub(*k.retiredAt) > ring.retention {
+			continue
+		}
+		jwk := JWK{Kid: k.kid, Use: "sig", Alg: k.method.Alg()}
+		switch public := k.public.(type) {
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
@@ -0,0 +1,345 @@
+// Package signing issues and verifies the JWTs of the API. Tokens are signed
+// with asymmetric keys named by a kid in their header, and the public keys are
+// published as a JWK set so that other services can verify tokens without
+// sharing a secret. The keys live in the database, so that every instance of
+// the API signs with the same key, and are rotated on a schedule.
+package signing
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"gorm.io/gorm"
+
+	"backend/models"
+)
+
+// Supported signing algorithms.
+const (
+	AlgEdDSA = "EdDSA"
+	AlgRS256 = "RS256"
+)
+
+const (
+	// DefaultRotationInterval is how long a key signs tokens before it is replaced.
+	DefaultRotationInterval = 30 * 24 * time.Hour
+	// DefaultRetention is how long a retired key still verifies tokens. It
+	// must outlast the tokens signed with it.
+	DefaultRetention = 24 * time.Hour
+
+	// rsaKeyBits is the size of generated RSA keys.
+	rsaKeyBits = 2048
+	// reloadInterval is how often at most a token with an unknown kid makes
+	// the keys reload, to pick up a key another instance has just created.
+	reloadInterval = 10 * time.Second
+	// rotationLockID is the Postgres advisory lock serialising rotations
+	// across instances.
+	rotationLockID = 7207240
+)
+
+// ErrNoSigningKey is returned by Sign before Init has loaded a key.
+var ErrNoSigningKey = errors.New("no signing key loaded")
+
+// key is a loaded SigningKey.
+type key struct {
+	kid       string
+	method    jwt.SigningMethod
+	private   crypto.Signer
+	public    crypto.PublicKey
+	retiredAt *time.Time
+}
+
+// keyRing holds the keys of this instance.
+type keyRing struct {
+	mu           sync.RWMutex
+	db           *gorm.DB
+	alg          string
+	rotation     time.Duration
+	retention    time.Duration
+	cookieSecret []byte
+	active       *key
+	keys         map[string]*key
+	loadedAt     time.Time
+}
+
+var ring keyRing
+
+// Init reads the signing configuration from the environment, creates a key
+// when none is due to sign yet and loads the keys.
+//
+//	JWT_SIGNING_ALG     EdDSA (default) or RS256
+//	JWT_KEY_ROTATION    how long a key signs tokens, e.g. 720h
+//	JWT_KEY_RETENTION   how long a retired key still verifies tokens, e.g. 24h
+//	JWT_SECRET          secret of the NextAuth session cookie
+func Init(db *gorm.DB) error {
+	alg := os.Getenv("JWT_SIGNING_ALG")
+	if alg == "" {
+		alg = AlgEdDSA
+	}
+	if alg != AlgEdDSA && alg != AlgRS256 {
+		return fmt.Errorf("JWT_SIGNING_ALG must be %s or %s", AlgEdDSA, AlgRS256)
+	}
+	rotation, err := durationEnv("JWT_KEY_ROTATION", DefaultRotationInterval)
+	if err != nil {
+		return err
+	}
+	retention, err := durationEnv("JWT_KEY_RETENTION", DefaultRetention)
+	if err != nil {
+		return err
+	}
+	cookieSecret := []byte(os.Getenv("JWT_SECRET"))
+	if len(cookieSecret) == 0 {
+		log.Println("Warning: JWT_SECRET is not set, session cookies will be rejected")
+	}
+
+	ring.mu.Lock()
+	ring.db = db
+	ring.alg = alg
+	ring.rotation = rotation
+	ring.retention = retention
+	ring.cookieSecret = cookieSecret
+	ring.mu.Unlock()
+
+	return Rotate(db, time.Now())
+}
+
+// durationEnv parses the duration in the environment variable name, or returns def when it is unset.
+func durationEnv(name string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration such as 720h", name)
+	}
+	return d, nil
+}
+
+// Rotate replaces the signing key when it is older than the rotation interval
+// or uses another algorithm than configured, deletes keys retired longer ago
+// than the retention period and reloads the keys. Concurrent rotations by
+// several instances create a single key.
+func Rotate(db *gorm.DB, now time.Time) error {
+	ring.mu.RLock()
+	alg, rotation, retention := ring.alg, ring.rotation, ring.retention
+	ring.mu.RUnlock()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", rotationLockID).Error; err != nil {
+			return err
+		}
+		var current models.SigningKey
+		err := tx.Where("retired_at IS NULL").Order("created_at DESC").First(&current).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil && current.Algorithm == alg && now.Sub(current.CreatedAt) < rotation {
+			return nil
+		}
+
+		next, err := generateKey(alg)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.SigningKey{}).
+			Where("retired_at IS NULL AND id <> ?", next.ID).
+			Update("retired_at", now).Error; err != nil {
+			return err
+		}
+		log.Printf("Rotated JWT signing key, now signing with %s", next.Kid)
+		return tx.Where("retired_at < ?", now.Add(-retention)).Delete(&models.SigningKey{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	return load(db, now)
+}
+
+// generateKey returns a new key pair for the algorithm.
+func generateKey(alg string) (*models.SigningKey, error) {
+	var private crypto.Signer
+	var err error
+	switch alg {
+	case AlgEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	case AlgRS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		return nil, err
+	}
+	kid := make([]byte, 8)
+	if _, err := rand.Read(kid); err != nil {
+		return nil, err
+	}
+	return &models.SigningKey{
+		Kid:        hex.EncodeToString(kid),
+		Algorithm:  alg,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})),
+		PublicKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+	}, nil
+}
+
+// load replaces the keys of this instance with the ones in the database that
+// sign or still verify tokens.
+func load(db *gorm.DB, now time.Time) error {
+	ring.mu.RLock()
+	retention := ring.retention
+	ring.mu.RUnlock()
+
+	var rows []models.SigningKey
+	if err := db.Where("retired_at IS NULL OR retired_at > ?", now.Add(-retention)).
+		Order("created_at").Find(&rows).Error; err != nil {
+		return err
+	}
+	keys := make(map[string]*key, len(rows))
+	var active *key
+	for _, row := range rows {
+		k, err := parseKey(row)
+		if err != nil {
+			log.Printf("Skipping JWT signing key %s: %v", row.Kid, err)
+			continue
+		}
+		keys[k.kid] = k
+		if k.retiredAt == nil {
+			active = k
+		}
+	}
+
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+	ring.keys = keys
+	ring.loadedAt = now
+	if active != nil {
+		ring.active = active
+	}
+	return nil
+}
+
+// parseKey decodes a stored key pair.
+func parseKey(row models.SigningKey) (*key, error) {
+	var method jwt.SigningMethod
+	switch row.Algorithm {
+	case AlgEdDSA:
+		method = jwt.SigningMethodEdDSA
+	case AlgRS256:
+		method = jwt.SigningMethodRS256
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", row.Algorithm)
+	}
+	block, _ := pem.Decode([]byte(row.PrivateKey))
+	if block == nil {
+		return nil, errors.New("invalid private key PEM")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	private, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key cannot sign")
+	}
+	return &key{
+		kid:       row.Kid,
+		method:    method,
+		private:   private,
+		public:    private.Public(),
+		retiredAt: row.RetiredAt,
+	}, nil
+}
+
+// Sign returns the claims as a JWT signed with the current key.
+func Sign(claims jwt.MapClaims) (string, error) {
+	ring.mu.RLock()
+	active := ring.active
+	ring.mu.RUnlock()
+	if active == nil {
+		return "", ErrNoSigningKey
+	}
+	token := jwt.NewWithClaims(active.method, claims)
+	token.Header["kid"] = active.kid
+	return token.SignedString(active.private)
+}
+
+// Parse verifies a JWT issued by Sign. Tokens signed with a key retired
+// longer ago than the retention period are rejected.
+func Parse(tokenString string) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		k := lookup(kid)
+		if k == nil {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if token.Method.Alg() != k.method.Alg() {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return k.public, nil
+	})
+}
+
+// lookup returns the key verifying tokens with the kid, reloading the keys
+// when it is unknown in case another instance has just rotated them.
+func lookup(kid string) *key {
+	if kid == "" {
+		return nil
+	}
+	now := time.Now()
+	ring.mu.RLock()
+	k := ring.keys[kid]
+	db, retention, loadedAt := ring.db, ring.retention, ring.loadedAt
+	ring.mu.RUnlock()
+
+	if k == nil && db != nil && now.Sub(loadedAt) >= reloadInterval {
+		if err := load(db, now); err != nil {
+			log.Println("Error reloading JWT signing keys:", err)
+			return nil
+		}
+		ring.mu.RLock()
+		k = ring.keys[kid]
+		ring.mu.RUnlock()
+	}
+	if k == nil || (k.retiredAt != nil && now.Sub(*k.retiredAt) > retention) {
+		return nil
+	}
+	return k
+}
+
+// ParseSessionCookie verifies the NextAuth session cookie. NextAuth signs it
+// itself, with the HS256 secret it shares with the API as JWT_SECRET.
+func ParseSessionCookie(tokenString string) (*jwt.Token, error) {
+	ring.mu.RLock()
+	secret := ring.cookieSecret
+	ring.mu.RUnlock()
+	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if len(secret) == 0 {
+			return nil, errors.New("no session cookie secret configured")
+		}
+		return secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+}
@@ -0,0 +1,221 @@
+package signing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"backend/models"
+)
+
+// newTestKey generates a key pair for alg as stored in the database and loaded.
+func newTestKey(t *testing.T, alg string) *key {
+	t.Helper()
+	row, err := generateKey(alg)
+	if err != nil {
+		t.Fatalf("generateKey(%s): %v", alg, err)
+	}
+	k, err := parseKey(*row)
+	if err != nil {
+		t.Fatalf("parseKey(%s): %v", alg, err)
+	}
+	return k
+}
+
+// useKeys loads keys into the key ring for the test, signing with active.
+func useKeys(t *testing.T, active *key, keys ...*key) {
+	t.Helper()
+	loaded := map[string]*key{}
+	for _, k := range append(keys, active) {
+		if k != nil {
+			loaded[k.kid] = k
+		}
+	}
+	ring.mu.Lock()
+	db, retention, savedActive, savedKeys, loadedAt := ring.db, ring.retention, ring.active, ring.keys, ring.loadedAt
+	ring.db, ring.retention, ring.active, ring.keys, ring.loadedAt = nil, time.Hour, active, loaded, time.Now()
+	ring.mu.Unlock()
+	t.Cleanup(func() {
+		ring.mu.Lock()
+		defer ring.mu.Unlock()
+		ring.db, ring.retention, ring.active, ring.keys, ring.loadedAt = db, retention, savedActive, savedKeys, loadedAt
+	})
+}
+
+func TestGenerateAndParseKey(t *testing.T) {
+	for _, alg := range []string{AlgEdDSA, AlgRS256} {
+		t.Run(alg, func(t *testing.T) {
+			k := newTestKey(t, alg)
+			if len(k.kid) != 16 || k.method.Alg() != alg {
+				t.Errorf("kid %q, method %s; want 16 hex digits and %s", k.kid, k.method.Alg(), alg)
+			}
+		})
+	}
+	if _, err := generateKey("HS256"); err == nil {
+		t.Error("generateKey(HS256) succeeded, want an error")
+	}
+	if _, err := parseKey(models.SigningKey{Algorithm: AlgEdDSA, PrivateKey: "not a key"}); err == nil {
+		t.Error("parseKey accepted an invalid PEM block")
+	}
+}
+
+func TestSignAndParse(t *testing.T) {
+	retiredLongAgo := time.Now().Add(-2 * time.Hour)
+	retiredRecently := time.Now().Add(-time.Minute)
+
+	current := newTestKey(t, AlgEdDSA)
+	rsaKey := newTestKey(t, AlgRS256)
+	recent := newTestKey(t, AlgEdDSA)
+	recent.retiredAt = &retiredRecently
+	expired := newTestKey(t, AlgEdDSA)
+	expired.retiredAt = &retiredLongAgo
+	unknown := newTestKey(t, AlgEdDSA)
+
+	claims := jwt.MapClaims{"sub": "1", "exp": time.Now().Add(time.Minute).Unix()}
+	tokens := map[*key]string{}
+	for _, k := range []*key{current, rsaKey, recent, expired, unknown} {
+		useKeys(t, k)
+		token, err := Sign(claims)
+		if err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+		tokens[k] = token
+	}
+	useKeys(t, current, rsaKey, recent, expired)
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"current key", tokens[current], true},
+		{"other algorithm still loaded", tokens[rsaKey], true},
+		{"recently retired key", tokens[recent], true},
+		{"key retired beyond the retention", tokens[expired], false},
+		{"unknown key", tokens[unknown], false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := Parse(tt.token)
+			if valid := err == nil && token.Valid; valid != tt.valid {
+				t.Errorf("Parse valid = %v (%v), want %v", valid, err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestParseRejectsAlgorithmOfAnotherKey(t *testing.T) {
+	edKey := newTestKey(t, AlgEdDSA)
+	rsaKey := newTestKey(t, AlgRS256)
+	useKeys(t, rsaKey, edKey)
+	token, err := Sign(jwt.MapClaims{"sub": "1"})
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	// Name the EdDSA key in the header of a token signed with the RSA key.
+	parsed, _, err := new(jwt.Parser).ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		t.Fatalf("ParseUnverified: %v", err)
+	}
+	parsed.Header["kid"] = edKey.kid
+	forged, err := parsed.SignedString(rsaKey.private)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	if _, err := Parse(forged); err == nil {
+		t.Error("Parse accepted a token whose algorithm does not match its key")
+	}
+}
+
+func TestSignWithoutKey(t *testing.T) {
+	useKeys(t, nil)
+	if _, err := Sign(jwt.MapClaims{}); err != ErrNoSigningKey {
+		t.Errorf("Sign error = %v, want ErrNoSigningKey", err)
+	}
+}
+
+func TestPublicKeys(t *testing.T) {
+	retiredLongAgo := time.Now().Add(-2 * time.Hour)
+	current := newTestKey(t, AlgEdDSA)
+	rsaKey := newTestKey(t, AlgRS256)
+	expired := newTestKey(t, AlgEdDSA)
+	expired.retiredAt = &retiredLongAgo
+	useKeys(t, current, rsaKey, expired)
+
+	set := PublicKeys()
+	if len(set.Keys) != 2 {
+		t.Fatalf("%d keys published, want 2: %+v", len(set.Keys), set.Keys)
+	}
+	for _, jwk := range set.Keys {
+		switch jwk.Kid {
+		case current.kid:
+			if jwk.Kty != "OKP" || jwk.Crv != "Ed25519" || jwk.X == "" || jwk.Alg != AlgEdDSA {
+				t.Errorf("Ed25519 key = %+v", jwk)
+			}
+		case rsaKey.kid:
+			if jwk.Kty != "RSA" || jwk.N == "" || jwk.E != "AQAB" || jwk.Alg != AlgRS256 {
+				t.Errorf("RSA key = %+v", jwk)
+			}
+		default:
+			t.Errorf("unexpected key %s published", jwk.Kid)
+		}
+	}
+}
+
+func TestDurationEnv(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", time.Hour, false},
+		{"720h", 720 * time.Hour, false},
+		{"0s", 0, true},
+		{"-1h", 0, true},
+		{"soon", 0, true},
+	}
+	for _, tt := range tests {
+		t.Setenv("JWT_TEST_DURATION", tt.value)
+		got, err := durationEnv("JWT_TEST_DURATION", time.Hour)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("durationEnv(%q) = %v, %v; want %v, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseSessionCookie(t *testing.T) {
+	ring.mu.Lock()
+	saved := ring.cookieSecret
+	ring.cookieSecret = []byte("cookie secret")
+	ring.mu.Unlock()
+	t.Cleanup(func() {
+		ring.mu.Lock()
+		defer ring.mu.Unlock()
+		ring.cookieSecret = saved
+	})
+
+	sign := func(method jwt.SigningMethod, secret interface{}) string {
+		token, err := jwt.NewWithClaims(method, jwt.MapClaims{"sub": "1"}).SignedString(secret)
+		if err != nil {
+			t.Fatalf("SignedString: %v", err)
+		}
+		return token
+	}
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"shared secret", sign(jwt.SigningMethodHS256, []byte("cookie secret")), true},
+		{"other secret", sign(jwt.SigningMethodHS256, []byte("guess")), false},
+		{"other HMAC algorithm", sign(jwt.SigningMethodHS512, []byte("cookie secret")), false},
+	}
+	for _, tt := range tests {
+		token, err := ParseSessionCookie(tt.token)
+		if valid := err == nil && token.Valid; valid != tt.valid {
+			t.Errorf("%s: valid = %v (%v), want %v", tt.name, valid, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// PDF page size (A4) in points.
+const (
+	PDFPageWidth  = 595.0
+	PDFPageHeight = 842.0
+)
+
+// PDF font styles available to PDFDocument.Text.
+const (
+	FontRegular = "F1" // Helvetica
+	FontBold    = "F2" // Helvetica-Bold
+	FontMono    = "F3" // Courier, used for aligned numbers
+)
+
+// fontJapanese is a non-embedded CID font from the Adobe-Japan1 collection.
+// It is picked automatically for text the WinAnsi fonts cannot show (e.g. Japanese company names).
+const fontJapanese = "F4"
+
+// PDFDocument is a minimal PDF writer producing text and lines on A4 pages.
+// Text the WinAnsi encoding covers uses the standard base fonts and other text
+// a Japanese CID font the viewer provides, so no font files are embedded.
+type PDFDocument struct {
+	pages []*bytes.Buffer
+}
+
+// NewPDFDocument returns a document with a single empty page.
+func NewPDFDocument() *PDFDocument {
+	d := &PDFDocument{}
+	d.AddPage()
+	return d
+}
+
+// AddPage starts a new page; subsequent drawing goes to it.
+func (d *PDFDocument) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+func (d *PDFDocument) page() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// Text draws s with its baseline starting at (x, y), measured from the
+// bottom-left corner of the page.
+func (d *PDFDocument) Text(x, y, size float64, font, s string) {
+	encoded, winAnsi := encodePDFString(s)
+	if !winAnsi {
+		font = fontJapanese
+	}
+	fmt.Fprintf(d.page(), "BT /%s %.2f Tf %.2f %.2f Td %s Tj ET\n", font, size, x, y, encoded)
+}
+
+// TextRight draws s in the monospaced font so that it ends at x.
+func (d *PDFDocument) TextRight(x, y, size float64, s string) {
+	// Courier glyphs are 600/1000 em wide.
+	width := float64(len([]rune(s))) * size * 0.6
+	d.Text(x-width, y, size, FontMono, s)
+}
+
+// Line draws a thin line from (x1, y1) to (x2, y2).
+func (d *PDFDocument) Line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(d.page(), "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, y1, x2, y2)
+}
+
+// Bytes serialises the document.
+func (d *PDFDocument) Bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+
+	// Objects are numbered in the order they are written.
+	writeObject := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// Fixed objects: 1 catalog, 2 page tree, 3-6 fonts, 7-8 Japanese font parts.
+	const firstPageObject = 9
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPageObject+2*i)
+	}
+
+	out.WriteString("%PDF-1.4\n")
+	writeObject("<< /Type /Catalog /Pages 2 0 R >>")
+	writeObject(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	writeObject("<< /Type /Font /Subtype /Type0 /BaseFont /HeiseiKakuGo-W5 /Encoding /UniJIS-UCS2-H /DescendantFonts [7 0 R] >>")
+	writeObject("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /HeiseiKakuGo-W5 " +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (Japan1) /Supplement 2 >> /FontDescriptor 8 0 R /DW 1000 >>")
+	writeObject("<< /Type /FontDescriptor /FontName /HeiseiKakuGo-W5 /Flags 4 /FontBBox [-92 -250 1010 922] " +
+		"/ItalicAngle 0 /Ascent 752 /Descent -221 /CapHeight 737 /StemV 114 >>")
+
+	for i, content := range d.pages {
+		writeObject(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R /F3 5 0 R /F4 6 0 R >> >> /Contents %d 0 R >>",
+			PDFPageWidth, PDFPageHeight, firstPageObject+2*i+1))
+		writeObject(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+// encodePDFString returns s as a PDF string operand. Text made of printable
+// ASCII and Latin-1 characters becomes a literal string for the WinAnsi fonts;
+// anything else becomes UTF-16 hex for the Japanese CID font. WinAnsi puts
+// other characters (€, ‚, ƒ...) at the bytes 0x80-0x9F, so those runes cannot
+// be written as they are.
+func encodePDFString(s string) (encoded string, winAnsi bool) {
+	winAnsi = true
+	for _, r := range s {
+		if !inWinAnsi(r) {
+			winAnsi = false
+			break
+		}
+	}
+
+	if winAnsi {
+		var b strings.Builder
+		b.WriteByte('(')
+		for _, r := range s {
+			switch r {
+			case '\\', '(', ')':
+				b.WriteByte('\\')
+				b.WriteRune(r)
+			case '\n', '\r', '\t':
+				b.WriteByte(' ')
+			default:
+				b.WriteByte(byte(r))
+			}
+		}
+		b.WriteByte(')')
+		return b.String(), true
+	}
+
+	var b strings.Builder
+	b.WriteByte('<')
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", u)
+	}
+	b.WriteByte('>')
+	return b.String(), false
+}
+
+// inWinAnsi reports whether the WinAnsi fonts show r as the byte of the same
+// value. Line breaks and tabs count, as they are written as spaces.
+func inWinAnsi(r rune) bool {
+	switch {
+	case r == '\n', r == '\r', r == '\t':
+		return true
+	case r >= 0x20 && r < 0x7F:
+		return true
+	case r >= 0xA0 && r <= 0xFF:
+		return true
+	}
+	return false
+}
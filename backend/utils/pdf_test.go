@@ -0,0 +1,27 @@
+package utils
+
+import "testing"
+
+func TestEncodePDFString(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		winAnsi bool
+	}{
+		{"ascii", "Invoice 12", "(Invoice 12)", true},
+		{"escapes", `a (b) \c`, `(a \(b\) \\c)`, true},
+		{"line breaks", "a\nb\tc", "(a b c)", true},
+		{"latin-1", "Café", "(Caf\xe9)", true},
+		{"c1 control", "a\u0080b", "<006100800062>", false},
+		{"japanese", "株式会社", "<682A5F0F4F1A793E>", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, winAnsi := encodePDFString(tt.in)
+			if got != tt.want || winAnsi != tt.winAnsi {
+				t.Errorf("encodePDFString(%q) = %q, %v; want %q, %v", tt.in, got, winAnsi, tt.want, tt.winAnsi)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// HashToken returns the stored form of a secret token. Tokens are random and
+// long, so a fast hash is enough; only the hash is ever kept.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewToken returns a random secret token starting with prefix, and its hash.
+func NewToken(prefix string) (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = prefix + hex.EncodeToString(b)
+	return token, HashToken(token), nil
+}
@@ -11,12 +11,20 @@ declare module "next-auth" {
       token?: string
       /** The associated company ID. */
       companyID?: number
+      /** The user's role within the company, e.g. "owner" or "warehouse". */
+      role?: string
     } & DefaultSession["user"]
+    /** Set when the backend session could not be renewed; the user must log in again. */
+    error?: string
   }
 
   interface User extends DefaultUser {
     /** The custom token from your backend. */
     token?: string
+    /** The refresh token renewing the backend token. */
+    refreshToken?: string
+    /** When the backend token expires, in milliseconds since the epoch. */
+    tokenExpires?: number
   }
 }
 
@@ -25,6 +33,12 @@ declare module "next-auth/jwt" {
   interface JWT {
     token?: string
     companyID?: number
+    userID?: number
+    role?: string
+    sid?: number
     email?: string
+    refreshToken?: string
+    tokenExpires?: number
+    error?: string
   }
 }
\ No newline at end of file
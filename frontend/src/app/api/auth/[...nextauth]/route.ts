@@ -3,6 +3,34 @@ import jwt from "jsonwebtoken";
 import CredentialsProvider from "next-auth/providers/credentials";
 import GoogleProvider from "next-auth/providers/google";
 import GithubProvider from "next-auth/providers/github";
+import { JWT } from "next-auth/jwt";
+
+// refreshBackendToken exchanges the refresh token for a new backend token.
+// Refresh tokens are single use, so the new one replaces it in the cookie.
+async function refreshBackendToken(token: JWT): Promise<JWT> {
+  try {
+    const res = await fetch(`${process.env.NEXT_PUBLIC_BACKEND_URL}/api/token/refresh/`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ refresh_token: token.refreshToken }),
+    });
+    if (!res.ok) {
+      throw new Error("Failed to refresh token");
+    }
+    const data = await res.json();
+    return {
+      ...token,
+      token: data.token,
+      refreshToken: data.refresh_token,
+      tokenExpires: Date.now() + data.expires_in * 1000,
+      role: data.role,
+      error: undefined,
+    };
+  } catch (error) {
+    console.error("Error refreshing backend token:", error);
+    return { ...token, error: "RefreshTokenError" };
+  }
+}
 
 const authOptions: NextAuthOptions = {
   providers: [
@@ -33,7 +61,13 @@ const authOptions: NextAuthOptions = {
         // The backend returns JSON which includes a JWT token and user info.
         // For example: { email: "test@test.com", token: "..." }
         const data = await res.json();
-        return { id: data.email, email: data.email, token: data.token };
+        return {
+          id: data.email,
+          email: data.email,
+          token: data.token,
+          refreshToken: data.refresh_token,
+          tokenExpires: Date.now() + data.expires_in * 1000,
+        };
       },
     }),
     // OAuth providers
@@ -84,17 +118,30 @@ const authOptions: NextAuthOptions = {
       if (user) {
         if (user.token) {
           token.token = user.token; // backend's JWT token
+          token.refreshToken = user.refreshToken;
+          token.tokenExpires = user.tokenExpires;
         }
         token.email = user.email ?? undefined;
-        // Decode the backend token to get the companyID claim.
+        // Decode the backend token to get the companyID, userID, role and session claims.
+        // The backend reads them from this session cookie. The token comes
+        // straight from the backend's login response, so it is not verified
+        // again here; the backend signs it with keys published at /.well-known/jwks.json.
         try {
-          const decoded = jwt.verify(user.token!, process.env.NEXTAUTH_SECRET!) as jwt.JwtPayload;
+          const decoded = jwt.decode(user.token!) as jwt.JwtPayload | null;
           if (decoded && decoded.companyID) {
             token.companyID = decoded.companyID;
+            token.userID = decoded.userID;
+            token.role = decoded.role;
+            token.sid = decoded.sid;
           }
         } catch (error) {
           console.error("Error decoding backend token:", error);
         }
+        return token;
+      }
+      // Renew the backend token shortly before it expires.
+      if (token.refreshToken && token.tokenExpires && Date.now() > token.tokenExpires - 60 * 1000) {
+        return refreshBackendToken(token);
       }
       return token;
     },
@@ -104,10 +151,29 @@ const authOptions: NextAuthOptions = {
         email: token.email,
         token: token.token,
         companyID: token.companyID,
+        role: token.role,
       }
+      session.error = token.error;
       return session;
     },
   },
+  events: {
+    // End the backend session too, so that its refresh token stops working.
+    async signOut({ token }) {
+      if (!token?.refreshToken) {
+        return;
+      }
+      try {
+        await fetch(`${process.env.NEXT_PUBLIC_BACKEND_URL}/api/token/revoke/`, {
+          method: "POST",
+          headers: { "Content-Type": "application/json" },
+          body: JSON.stringify({ refresh_token: token.refreshToken }),
+        });
+      } catch (error) {
+        console.error("Error revoking backend session:", error);
+      }
+    },
+  },
   secret: process.env.NEXTAUTH_SECRET,
 };
 
@@ -4,12 +4,20 @@ import { useMenu } from "../context/MenuContext";
 import Link from "next/link";
 import { signOut, useSession } from "next-auth/react";
 import { usePathname } from "next/navigation";
+import { useEffect } from "react";
 
 export default function Header() {
   const { menuOpen, setMenuOpen } = useMenu();
   const { data: session } = useSession();
   const pathname = usePathname();
 
+  // The backend session ended, e.g. after a password change elsewhere.
+  useEffect(() => {
+    if (session?.error === "RefreshTokenError") {
+      signOut({ callbackUrl: "/" });
+    }
+  }, [session?.error]);
+
   // Determine common header content
   const commonHeader = (
     <header className="fixed top-0 left-0 w-full z-[60] bg-white border-b border-gray-300 py-4">
@@ -4,11 +4,27 @@ import { useEffect, useState } from "react";
 import { useSession } from "next-auth/react";
 import { useRouter } from "next/navigation";
 
+// Amounts are exact decimal strings, e.g. "1234.50".
 interface CostData {
-  completedSpent: number;
-  completedEarned: number;
-  pendingSpent: number;
-  pendingEarned: number;
+  completedSpent: string;
+  completedEarned: string;
+  pendingSpent: string;
+  pendingEarned: string;
+}
+
+// toCents parses a decimal amount string into exact hundredths.
+function toCents(amount: string): number {
+  const negative = amount.startsWith("-");
+  const [whole, frac = ""] = amount.replace(/^[-+]/, "").split(".");
+  const cents = parseInt(whole || "0", 10) * 100 + parseInt((frac + "00").slice(0, 2), 10);
+  return negative ? -cents : cents;
+}
+
+// formatCents formats hundredths as a decimal amount string with two decimals.
+function formatCents(cents: number): string {
+  const sign = cents < 0 ? "-" : "";
+  const abs = Math.abs(cents);
+  return `${sign}${Math.floor(abs / 100)}.${String(abs % 100).padStart(2, "0")}`;
 }
 
 export default function Dashboard() {
@@ -16,10 +32,10 @@ export default function Dashboard() {
   const router = useRouter();
   const [loading, setLoading] = useState(true);
   const [costData, setCostData] = useState<CostData>({
-    completedSpent: 0,
-    completedEarned: 0,
-    pendingSpent: 0,
-    pendingEarned: 0,
+    completedSpent: "0.00",
+    completedEarned: "0.00",
+    pendingSpent: "0.00",
+    pendingEarned: "0.00",
   });
 
   useEffect(() => {
@@ -41,8 +57,14 @@ export default function Dashboard() {
         if (!res.ok) {
           throw new Error("Failed to fetch cost data");
         }
+        // Amounts arrive as decimal strings and are shown as they are.
         const data: CostData = await res.json();
-        setCostData(data);
+        setCostData({
+          completedSpent: data.completedSpent,
+          completedEarned: data.completedEarned,
+          pendingSpent: data.pendingSpent,
+          pendingEarned: data.pendingEarned,
+        });
       } catch (error) {
         console.error("Error fetching cost data:", error);
       } finally {
@@ -52,9 +74,9 @@ export default function Dashboard() {
     fetchCostData();
   }, [session]);
 
-  // Calculations:
-  const netCompleted = costData.completedEarned - costData.completedSpent;
-  const netPending = costData.pendingEarned - costData.pendingSpent;
+  // Calculations, in exact hundredths:
+  const netCompleted = toCents(costData.completedEarned) - toCents(costData.completedSpent);
+  const netPending = toCents(costData.pendingEarned) - toCents(costData.pendingSpent);
 
   // Apply conditional coloring:
   const netCompletedColor = netCompleted < 0 ? "text-red-500" : "text-green-500";
@@ -95,17 +117,17 @@ export default function Dashboard() {
         <div className="bg-white shadow p-6 rounded-lg">
           <h2 className="text-2xl font-bold mb-2 text-center">Net Profit (Completed Orders)</h2>
           <p className={`text-3xl font-bold text-center ${netCompletedColor}`}>
-            Total Amount: ${netCompleted.toFixed(2)}
+            Total Amount: ${formatCents(netCompleted)}
           </p>
         </div>
         <div className="grid grid-cols-2 gap-4">
           <div className="bg-white shadow p-4 rounded-lg text-center">
             <h3 className="font-bold">Cost Spent (Completed)</h3>
-            <p className="text-xl">${costData.completedSpent.toFixed(2)}</p>
+            <p className="text-xl">${costData.completedSpent}</p>
           </div>
           <div className="bg-white shadow p-4 rounded-lg text-center">
             <h3 className="font-bold">Cost Earned (Completed)</h3>
-            <p className="text-xl">${costData.completedEarned.toFixed(2)}</p>
+            <p className="text-xl">${costData.completedEarned}</p>
           </div>
         </div>
 
@@ -113,17 +135,17 @@ export default function Dashboard() {
         <div className="bg-white shadow p-6 rounded-lg">
           <h2 className="text-2xl font-bold mb-2 text-center">Net Pending Difference</h2>
           <p className={`text-3xl font-bold text-center ${netPendingColor}`}>
-            Total Amount: ${netPending.toFixed(2)}
+            Total Amount: ${formatCents(netPending)}
           </p>
         </div>
         <div className="grid grid-cols-2 gap-4">
           <div className="bg-white shadow p-4 rounded-lg text-center">
             <h3 className="font-bold">Cost to Spend (Pending)</h3>
-            <p className="text-xl">${costData.pendingSpent.toFixed(2)}</p>
+            <p className="text-xl">${costData.pendingSpent}</p>
           </div>
           <div className="bg-white shadow p-4 rounded-lg text-center">
             <h3 className="font-bold">Cost to Earn (Pending)</h3>
-            <p className="text-xl">${costData.pendingEarned.toFixed(2)}</p>
+            <p className="text-xl">${costData.pendingEarned}</p>
           </div>
         </div>
       </main>
@@ -0,0 +1,66 @@
+"use client";
+
+import Link from "next/link";
+import { useState } from "react";
+
+// ForgotPasswordPage asks the backend to email a password reset link.
+export default function ForgotPasswordPage() {
+  const [email, setEmail] = useState("");
+  const [message, setMessage] = useState("");
+  const [submitting, setSubmitting] = useState(false);
+
+  const handleSubmit = async (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault();
+    setMessage("");
+    setSubmitting(true);
+    try {
+      const res = await fetch("/api/password/forgot/", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ email }),
+      });
+      const data = await res.json().catch(() => null);
+      if (!res.ok) {
+        setMessage(data?.error || "Failed to send the reset link");
+      } else {
+        setMessage("If the email belongs to a user, a reset link has been sent successfully.");
+      }
+    } catch (error) {
+      console.error("Forgot password error:", error);
+      setMessage("Failed to send the reset link");
+    } finally {
+      setSubmitting(false);
+    }
+  };
+
+  return (
+    <div className="flex items-start justify-center bg-white p-6">
+      <div className="w-full max-w-md bg-gray-100 rounded-lg shadow-xl p-8">
+        <h1 className="text-center text-2xl font-bold mb-6">Forgot Password</h1>
+        <form onSubmit={handleSubmit} className="space-y-4">
+          <input
+            type="email"
+            placeholder="Email"
+            value={email}
+            onChange={(e) => setEmail(e.target.value)}
+            required
+            className="w-full p-3 rounded-md text-black border"
+          />
+          <button
+            type="submit"
+            disabled={submitting}
+            className="w-full bg-blue-500 text-white p-3 rounded-md hover:bg-blue-600 disabled:opacity-50"
+          >
+            {submitting ? "Sending..." : "Send Reset Link"}
+          </button>
+        </form>
+        {message && <p className={`mt-4 ${message.includes("successfully") ? "text-green-600" : "text-red-600"}`}>{message}</p>}
+        <p className="mt-4 text-center">
+          <Link href="/login">
+            <span className="text-blue-400 underline">Back to Login</span>
+          </Link>
+        </p>
+      </div>
+    </div>
+  );
+}
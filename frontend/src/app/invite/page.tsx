@@ -0,0 +1,88 @@
+"use client";
+
+import { Suspense, useState } from "react";
+import { useRouter, useSearchParams } from "next/navigation";
+
+// AcceptInvitation creates the invited user from the token in the invitation link.
+function AcceptInvitation() {
+  const router = useRouter();
+  const searchParams = useSearchParams();
+  const token = searchParams.get("token") || "";
+  const [name, setName] = useState("");
+  const [password, setPassword] = useState("");
+  const [message, setMessage] = useState("");
+  const [submitting, setSubmitting] = useState(false);
+
+  const handleAccept = async (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault();
+    setMessage("");
+    setSubmitting(true);
+    try {
+      const res = await fetch("/api/invitations/accept/", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ token, name, password }),
+      });
+      if (!res.ok) {
+        const errorData = await res.json().catch(() => null);
+        setMessage(errorData?.error || "Failed to accept the invitation");
+      } else {
+        setMessage("Account created successfully! Redirecting to login page...");
+        setTimeout(() => {
+          router.push("/login");
+        }, 1500);
+      }
+    } catch (error) {
+      console.error("Invitation error:", error);
+      setMessage("Failed to accept the invitation");
+    } finally {
+      setSubmitting(false);
+    }
+  };
+
+  return (
+    <div className="flex items-start justify-center bg-white p-6">
+      <div className="w-full max-w-md bg-gray-100 rounded-lg shadow-xl p-8">
+        <h1 className="text-center text-2xl font-bold mb-6">Join Your Company</h1>
+        {!token ? (
+          <p className="text-red-600">The invitation link is incomplete.</p>
+        ) : (
+          <form onSubmit={handleAccept} className="space-y-4">
+            <input
+              type="text"
+              placeholder="Your Name"
+              value={name}
+              onChange={(e) => setName(e.target.value)}
+              className="w-full p-3 rounded-md text-black border"
+            />
+            <input
+              type="password"
+              placeholder="Password (at least 8 characters)"
+              value={password}
+              onChange={(e) => setPassword(e.target.value)}
+              minLength={8}
+              required
+              className="w-full p-3 rounded-md text-black border"
+            />
+            <button
+              type="submit"
+              disabled={submitting}
+              className="w-full bg-blue-500 text-white p-3 rounded-md hover:bg-blue-600 disabled:opacity-50"
+            >
+              {submitting ? "Creating account..." : "Create Account"}
+            </button>
+          </form>
+        )}
+        {message && <p className={`mt-4 ${message.includes("successfully") ? "text-green-600" : "text-red-600"}`}>{message}</p>}
+      </div>
+    </div>
+  );
+}
+
+export default function InvitePage() {
+  return (
+    <Suspense>
+      <AcceptInvitation />
+    </Suspense>
+  );
+}
@@ -80,6 +80,11 @@ export default function LoginPage() {
             {submitting ? "Logging in..." : "Login with Email/Password"}
           </button>
           {errorMsg && <p className="text-center text-red-400">{errorMsg}</p>}
+          <p className="text-center">
+            <Link href="/forgot-password">
+              <span className="text-blue-400 underline">Forgot your password?</span>
+            </Link>
+          </p>
         </form>
         {/* 
         <div className="flex flex-col space-y-4 mt-10 mb-6">
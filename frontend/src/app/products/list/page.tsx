@@ -7,7 +7,7 @@ interface Product {
   id: number;
   product_name: string;
   sku: string;
-  price: number;
+  price: string; // decimal string, e.g. "1234.50"
   quantity: number;
   description: string;
   warehouse: string;
@@ -63,7 +63,7 @@ export default function ProductList() {
     const nameMatch = p.product_name
       .toLowerCase()
       .includes(filters.product_name.toLowerCase());
-    const priceMatch = filters.price ? p.price === Number(filters.price) : true;
+    const priceMatch = filters.price ? Number(p.price) === Number(filters.price) : true;
     const quantityMatch = filters.quantity ? p.quantity === Number(filters.quantity) : true;
     const warehouseMatch = p.warehouse
       .toLowerCase()
@@ -81,9 +81,10 @@ export default function ProductList() {
       return sortOrder === "asc" ? aVal - bVal : bVal - aVal;
     }
     if (typeof aVal === "string" && typeof bVal === "string") {
+      // Numeric collation keeps decimal strings such as prices in numeric order.
       return sortOrder === "asc"
-        ? aVal.localeCompare(bVal)
-        : bVal.localeCompare(aVal);
+        ? aVal.localeCompare(bVal, undefined, { numeric: true })
+        : bVal.localeCompare(aVal, undefined, { numeric: true });
     }
     return 0;
   });
@@ -6,7 +6,7 @@ interface Product {
   id: number;
   product_name: string;
   sku: string;
-  price: number;
+  price: string; // decimal string, e.g. "1234.50"
   quantity: number;
   description: string;
   warehouse: string;
@@ -45,7 +45,7 @@ export default function ProductUpdate() {
         .then((data: Product) => {
           setProductName(data.product_name);
           setSku(data.sku);
-          setPrice(data.price);
+          setPrice(Number(data.price));
           setQuantity(data.quantity);
           setDescription(data.description);
           setWarehouseDisplay(data.warehouse);
@@ -7,10 +7,19 @@ interface Product {
   id: number;
   product_name: string;
   sku: string;
-  price: number;
+  price: string; // decimal string, e.g. "1234.50"
+  contract_price?: string; // the buyer's price list price, when one applies
   supplier_name: string;
 }
 
+// A cart line the server refused, see CreateOrderHandler.
+interface OrderLineError {
+  index: number;
+  product_id: number;
+  reason: string;
+  message: string;
+}
+
 interface CartItem {
   product: Product;
   quantity: number;
@@ -19,6 +28,7 @@ interface CartItem {
 export default function CartPage() {
   const [cartItems, setCartItems] = useState<CartItem[]>([]);
   const [orderMessage, setOrderMessage] = useState("");
+  const [lineErrors, setLineErrors] = useState<{ [productId: number]: string }>({});
 
   // Load cart items from localStorage on mount.
   useEffect(() => {
@@ -31,6 +41,7 @@ export default function CartPage() {
   const handleOrder = async () => {
     if (cartItems.length === 0) return;
     setOrderMessage("");
+    setLineErrors({});
   
     const orderPayload = {
       items: cartItems.map((item) => ({
@@ -54,6 +65,11 @@ export default function CartPage() {
     } else {
       const errData = await res.json().catch(() => null);
       setOrderMessage(errData?.error || "Failed to place order.");
+      const errors: { [productId: number]: string } = {};
+      (errData?.items || []).forEach((line: OrderLineError) => {
+        if (line.message) errors[line.product_id] = line.message;
+      });
+      setLineErrors(errors);
     }
   };
 
@@ -61,10 +77,15 @@ export default function CartPage() {
     const updatedCart = cartItems.filter(item => item.product.id !== productId);
     setCartItems(updatedCart);
     localStorage.setItem("cartItems", JSON.stringify(updatedCart));
+    setLineErrors((prev) => {
+      const next = { ...prev };
+      delete next[productId];
+      return next;
+    });
   };
 
   const totalPrice = cartItems.reduce(
-    (total, item) => total + item.product.price * item.quantity,
+    (total, item) => total + Number(item.product.contract_price ?? item.product.price) * item.quantity,
     0
   );
 
@@ -97,12 +118,17 @@ export default function CartPage() {
             </thead>
             <tbody>
               {cartItems.map((item) => (
-                <tr key={item.product.id}>
-                  <td className="border p-2">{item.product.product_name}</td>
-                  <td className="border p-2">${item.product.price}</td>
+                <tr key={item.product.id} className={lineErrors[item.product.id] ? "bg-red-100" : ""}>
+                  <td className="border p-2">
+                    {item.product.product_name}
+                    {lineErrors[item.product.id] && (
+                      <p className="text-sm text-red-600">{lineErrors[item.product.id]}</p>
+                    )}
+                  </td>
+                  <td className="border p-2">${item.product.contract_price ?? item.product.price}</td>
                   <td className="border p-2">{item.quantity}</td>
                   <td className="border p-2">
-                    ${(item.product.price * item.quantity).toFixed(2)}
+                    ${(Number(item.product.contract_price ?? item.product.price) * item.quantity).toFixed(2)}
                   </td>
                   <td className="border p-2">
                     <button 
@@ -9,7 +9,8 @@ interface Product {
   id: number;
   product_name: string;
   sku: string;
-  price: number;
+  price: string; // decimal string, e.g. "1234.50"
+  contract_price?: string; // the buyer's price list price, when one applies
   quantity: number;
   description: string;
   warehouse: string;
@@ -27,13 +28,13 @@ interface OrderItem {
   id?: number;
   product_id: number;
   quantity: number;
-  price: number;
+  price: string; // decimal string, e.g. "1234.50"
 }
 
 interface Order {
   id?: number;
   company_id: number;
-  total: number;
+  total: string;
   status: string;
   date: string;
   OrderItems?: OrderItem[];
@@ -44,6 +45,7 @@ export default function PurchasePage() {
   const [products, setProducts] = useState<Product[]>([]);
   const [loading, setLoading] = useState(true);
   const [orderMessage, setOrderMessage] = useState("");
+  const [couponCodes, setCouponCodes] = useState("");
   const [cartItems, setCartItems] = useState<CartItem[]>(() => {
     if (typeof window !== "undefined") {
       try {
@@ -102,7 +104,7 @@ export default function PurchasePage() {
   const filteredProducts = products.filter((p) => {
     const supplierMatch = p.supplier_name.toLowerCase().includes(filters.supplier_name.toLowerCase());
     const nameMatch = p.product_name.toLowerCase().includes(filters.product_name.toLowerCase());
-    const priceMatch = filters.price ? p.price === Number(filters.price) : true;
+    const priceMatch = filters.price ? Number(p.price) === Number(filters.price) : true;
     return supplierMatch && nameMatch && priceMatch;
   });
   const sortedProducts = [...filteredProducts].sort((a, b) => {
@@ -112,7 +114,10 @@ export default function PurchasePage() {
       return sortOrder === "asc" ? aVal - bVal : bVal - aVal;
     }
     if (typeof aVal === "string" && typeof bVal === "string") {
-      return sortOrder === "asc" ? aVal.localeCompare(bVal) : bVal.localeCompare(aVal);
+      // Numeric collation keeps decimal strings such as prices in numeric order.
+      return sortOrder === "asc"
+        ? aVal.localeCompare(bVal, undefined, { numeric: true })
+        : bVal.localeCompare(aVal, undefined, { numeric: true });
     }
     return 0;
   });
@@ -160,6 +165,10 @@ export default function PurchasePage() {
         product_id: item.product.id,
         quantity: item.quantity,
       })),
+      coupon_codes: couponCodes
+        .split(",")
+        .map((code) => code.trim())
+        .filter((code) => code !== ""),
     };
     const res = await fetch("/api/orders/", {
       method: "POST",
@@ -170,6 +179,7 @@ export default function PurchasePage() {
     if (res.ok) {
       setOrderMessage("Order placed successfully!");
       setCartItems([]);
+      setCouponCodes("");
       localStorage.removeItem("cartItems");
       // Refresh the order history.
       const ordersRes = await fetch("/api/orders/", { credentials: "include" });
@@ -247,7 +257,7 @@ export default function PurchasePage() {
               <tr key={orderKey}>
                 <td className="border p-2">{order.id ?? "N/A"}</td>
                 <td className="border p-2">{new Date(order.date).toLocaleString()}</td>
-                <td className="border p-2">{order.total.toFixed(2)}</td>
+                <td className="border p-2">{order.total}</td>
                 <td className="border p-2">{order.status}</td>
                 <td className="border p-2">
                   {(order.OrderItems || []).map((item, i) => {
@@ -343,7 +353,7 @@ export default function PurchasePage() {
                 <tr key={product.id}>
                   <td className="border p-2">{product.supplier_name}</td>
                   <td className="border p-2">{product.product_name}</td>
-                  <td className="border p-2">{product.price}</td>
+                  <td className="border p-2">{product.contract_price ?? product.price}</td>
                   <td className="border p-2">
                     <input
                       type="number"
@@ -370,8 +380,15 @@ export default function PurchasePage() {
           </div>
           )}
           <div className="flex justify-end items-center my-4">
+            <input
+              type="text"
+              placeholder="Coupon codes (comma separated)"
+              value={couponCodes}
+              onChange={(e) => setCouponCodes(e.target.value)}
+              className="border px-2 py-1 mr-4"
+            />
             <span className="mr-4 font-bold">
-              Total: ${cartItems.reduce((t, i) => t + i.product.price * i.quantity, 0).toFixed(2)}
+              Total: ${cartItems.reduce((t, i) => t + Number(i.product.contract_price ?? i.product.price) * i.quantity, 0).toFixed(2)}
             </span>
             <button
               onClick={handleOrder}
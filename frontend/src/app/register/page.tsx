@@ -39,7 +39,7 @@ export default function Register() {
         const errorData = await res.json();
         setMessage(errorData.error || "Registration failed");
       } else {
-        setMessage("Registration successful! Redirecting to login page...");
+        setMessage("Registration successful! Check your email to verify it. Redirecting to login page...");
         setTimeout(() => {
           router.push("/login");
         }, 1500);
@@ -142,4 +142,4 @@ export default function Register() {
       </div>
     </div>
   );
-}
\ No newline at end of file
+}
\ No newline at end of file
@@ -0,0 +1,134 @@
+'use client';
+
+import { useEffect, useState } from "react";
+
+interface OutgoingRequest {
+  ID: number;
+  CreatedAt: string;
+  seller_name: string;
+  status: string;
+  expires_at?: string;
+}
+
+const PAGE_SIZE = 20;
+
+export default function OutgoingRequests() {
+  const [requests, setRequests] = useState<OutgoingRequest[]>([]);
+  const [company, setCompany] = useState("");
+  const [status, setStatus] = useState("");
+  const [page, setPage] = useState(1);
+  const [total, setTotal] = useState(0);
+  const [message, setMessage] = useState("");
+
+  const load = async (targetPage: number) => {
+    try {
+      const queryParams = new URLSearchParams({ page: String(targetPage), page_size: String(PAGE_SIZE) });
+      if (company) queryParams.append("company", company);
+      if (status) queryParams.append("status", status);
+
+      const res = await fetch(`/api/requests/outgoing/?${queryParams.toString()}`, { credentials: "include" });
+      if (!res.ok) throw new Error("Failed to fetch requests");
+      setRequests(await res.json());
+      setTotal(Number(res.headers.get("X-Total-Count") || 0));
+      setPage(targetPage);
+    } catch (error) {
+      console.error(error);
+      setMessage("Failed to load requests.");
+    }
+  };
+
+  useEffect(() => {
+    load(1);
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, []);
+
+  const handleWithdraw = async (requestId: number) => {
+    try {
+      const res = await fetch(`/api/requests/${requestId}/withdraw/`, {
+        method: "PUT",
+        credentials: "include",
+      });
+      const data = await res.json().catch(() => null);
+      if (!res.ok) {
+        setMessage(data?.error || "Withdraw failed.");
+        return;
+      }
+      setMessage("Request withdrawn successfully.");
+      setRequests(prev => prev.map(r => r.ID === requestId ? { ...r, status: data.request.status } : r));
+    } catch (error) {
+      console.error(error);
+      setMessage("An error occurred.");
+    }
+  };
+
+  const pages = Math.max(1, Math.ceil(total / PAGE_SIZE));
+
+  return (
+    <div className="p-4">
+      <h2 className="text-2xl mb-4">My Requests</h2>
+      <div className="flex gap-2 mb-4">
+        <input
+          type="text"
+          placeholder="Seller name"
+          value={company}
+          onChange={(e) => setCompany(e.target.value)}
+          className="border p-2 rounded"
+        />
+        <select value={status} onChange={(e) => setStatus(e.target.value)} className="border p-2 rounded">
+          <option value="">All statuses</option>
+          <option value="pending">Pending</option>
+          <option value="permitted">Permitted</option>
+          <option value="rejected">Rejected</option>
+          <option value="revoked">Revoked</option>
+          <option value="withdrawn">Withdrawn</option>
+          <option value="expired">Expired</option>
+        </select>
+        <button onClick={() => load(1)} className="bg-blue-500 text-white px-4 py-2 rounded hover:bg-blue-600">
+          Search
+        </button>
+      </div>
+      {message && <p className={`mb-4 ${message.includes("successfully") ? "text-green-600" : "text-red-600"}`}>{message}</p>}
+      {requests.length === 0 ? (
+        <p>No requests sent.</p>
+      ) : (
+        <table className="w-full border">
+          <thead>
+            <tr className="bg-gray-100">
+              <th className="p-2 text-left">Seller</th>
+              <th className="p-2 text-left">Sent</th>
+              <th className="p-2 text-left">Status</th>
+              <th className="p-2 text-left">Expires</th>
+              <th className="p-2"></th>
+            </tr>
+          </thead>
+          <tbody>
+            {requests.map(req => (
+              <tr key={req.ID} className="border-t">
+                <td className="p-2">{req.seller_name}</td>
+                <td className="p-2">{new Date(req.CreatedAt).toLocaleDateString()}</td>
+                <td className="p-2">{req.status}</td>
+                <td className="p-2">{req.expires_at ? new Date(req.expires_at).toLocaleDateString() : "-"}</td>
+                <td className="p-2">
+                  {req.status === "pending" && (
+                    <button onClick={() => handleWithdraw(req.ID)} className="bg-red-500 text-white px-2 py-1 rounded">
+                      Withdraw
+                    </button>
+                  )}
+                </td>
+              </tr>
+            ))}
+          </tbody>
+        </table>
+      )}
+      <div className="flex items-center gap-2 mt-4">
+        <button disabled={page <= 1} onClick={() => load(page - 1)} className="border px-2 py-1 rounded disabled:opacity-50">
+          Previous
+        </button>
+        <span>Page {page} of {pages}</span>
+        <button disabled={page >= pages} onClick={() => load(page + 1)} className="border px-2 py-1 rounded disabled:opacity-50">
+          Next
+        </button>
+      </div>
+    </div>
+  );
+}
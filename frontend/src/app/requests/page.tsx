@@ -4,12 +4,14 @@ import { useState } from "react";
 import Tabs, { Tab } from "../components/Tabs";
 import SendRequest from "./send/page";
 import PermissionSearch from "./search/page";
+import OutgoingRequests from "./outgoing/page";
 
 export default function RequestPage() {
   const [initialTab] = useState(0);
 
   const tabs: Tab[] = [
     { label: "Send Request", content: <SendRequest />, },
+    { label: "My Requests", content: <OutgoingRequests />, },
     { label: "Search Requests", content: <PermissionSearch />, },
   ];
 
@@ -2,31 +2,64 @@
 
 import { useState } from "react";
 
+interface PermissionScope {
+  product_id?: number;
+  category?: string;
+  warehouse_id?: number;
+}
+
+interface PermissionMessage {
+  id: number;
+  author_email: string;
+  body: string;
+  created_at: string;
+}
+
 interface PermissionRequest {
   ID: number;
+  CreatedAt: string;
+  requester_name: string;
   requester_email: string;
   requester_phone: string;
   status: string;
+  scopes?: PermissionScope[]; // empty shares the whole catalog
+  messages?: PermissionMessage[];
 }
 
+const PAGE_SIZE = 20;
+
 export default function PermissionSearch() {
   const [email, setEmail] = useState("");
   const [phone, setPhone] = useState("");
+  const [text, setText] = useState("");
+  const [company, setCompany] = useState("");
+  const [status, setStatus] = useState("");
+  const [sort, setSort] = useState("created_at");
+  const [page, setPage] = useState(1);
+  const [total, setTotal] = useState(0);
   const [results, setResults] = useState<PermissionRequest[]>([]);
   const [message, setMessage] = useState("");
   const [searched, setSearched] = useState(false);
+  const [categoryInputs, setCategoryInputs] = useState<{ [requestId: number]: string }>({});
+  const [replies, setReplies] = useState<{ [requestId: number]: string }>({});
 
-  const handleSearch = async () => {
+  const handleSearch = async (targetPage = 1) => {
     try {
       setSearched(true);
-      const queryParams = new URLSearchParams();
+      const queryParams = new URLSearchParams({ page: String(targetPage), page_size: String(PAGE_SIZE), sort });
+      if (sort === "company") queryParams.append("order", "asc");
+      if (company) queryParams.append("company", company);
+      if (status) queryParams.append("status", status);
       if (email) queryParams.append("email", email);
       if (phone) queryParams.append("phone", phone);
+      if (text) queryParams.append("message", text);
 
       const res = await fetch(`/api/requests/search?${queryParams.toString()}`, { credentials: "include" });
       if (!res.ok) throw new Error("Search failed");
       const data = await res.json();
       setResults(data);
+      setTotal(Number(res.headers.get("X-Total-Count") || 0));
+      setPage(targetPage);
     } catch (error) {
       console.error(error);
       setMessage("Search error.");
@@ -57,6 +90,59 @@ export default function PermissionSearch() {
     }
   };
 
+  // Replaces the shared categories, keeping the product and warehouse scopes.
+  const handleSaveCategories = async (req: PermissionRequest) => {
+    const scopes = req.scopes || [];
+    const input = categoryInputs[req.ID] ?? scopes.filter(s => s.category).map(s => s.category).join(", ");
+    const scope = {
+      product_ids: scopes.filter(s => s.product_id).map(s => s.product_id),
+      warehouse_ids: scopes.filter(s => s.warehouse_id).map(s => s.warehouse_id),
+      categories: input.split(",").map(c => c.trim()).filter(c => c !== ""),
+    };
+    try {
+      const res = await fetch(`/api/requests/${req.ID}/`, {
+        method: "PUT",
+        headers: { "Content-Type": "application/json" },
+        credentials: "include",
+        body: JSON.stringify({ scope }),
+      });
+      const data = await res.json().catch(() => null);
+      if (!res.ok) {
+        setMessage(data?.error || "Update failed.");
+        return;
+      }
+      setMessage("Shared catalog updated successfully.");
+      setResults(prev => prev.map(r => r.ID === req.ID ? data.request : r));
+    } catch (error) {
+      console.error(error);
+      setMessage("An error occurred.");
+    }
+  };
+
+  const handleReply = async (requestId: number) => {
+    try {
+      const res = await fetch(`/api/requests/${requestId}/messages/`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        credentials: "include",
+        body: JSON.stringify({ body: replies[requestId] || "" }),
+      });
+      const data = await res.json().catch(() => null);
+      if (!res.ok) {
+        setMessage(data?.error || "Failed to send message.");
+        return;
+      }
+      setMessage("Message sent successfully.");
+      setReplies(prev => ({ ...prev, [requestId]: "" }));
+      setResults(prev =>
+        prev.map(r => r.ID === requestId ? { ...r, messages: [...(r.messages || []), data] } : r)
+      );
+    } catch (error) {
+      console.error(error);
+      setMessage("An error occurred.");
+    }
+  };
+
   return (
     <div className="p-4">
       <h2 className="text-2xl mb-4">Search Permission Requests</h2>
@@ -64,7 +150,7 @@ export default function PermissionSearch() {
         <div>
           <label className="block mb-1">Email:</label>
           <input
-            type="email"
+            type="text"
             value={email}
             onChange={(e) => setEmail(e.target.value)}
             className="border p-2 w-full rounded"
@@ -79,8 +165,42 @@ export default function PermissionSearch() {
             className="border p-2 w-full rounded"
           />
         </div>
+        <div>
+          <label className="block mb-1">Company:</label>
+          <input
+            type="text"
+            value={company}
+            onChange={(e) => setCompany(e.target.value)}
+            className="border p-2 w-full rounded"
+          />
+        </div>
+        <div className="flex gap-2">
+          <select value={status} onChange={(e) => setStatus(e.target.value)} className="border p-2 rounded">
+            <option value="">All statuses</option>
+            <option value="pending">Pending</option>
+            <option value="permitted">Permitted</option>
+            <option value="rejected">Rejected</option>
+            <option value="revoked">Revoked</option>
+            <option value="withdrawn">Withdrawn</option>
+            <option value="expired">Expired</option>
+          </select>
+          <select value={sort} onChange={(e) => setSort(e.target.value)} className="border p-2 rounded">
+            <option value="created_at">Newest first</option>
+            <option value="updated_at">Recently updated</option>
+            <option value="company">Company name</option>
+          </select>
+        </div>
+        <div>
+          <label className="block mb-1">Message contains:</label>
+          <input
+            type="text"
+            value={text}
+            onChange={(e) => setText(e.target.value)}
+            className="border p-2 w-full rounded"
+          />
+        </div>
         <button
-          onClick={handleSearch}
+          onClick={() => handleSearch(1)}
           className="bg-blue-500 text-white px-4 py-2 rounded hover:bg-blue-600"
         >
           Search
@@ -94,9 +214,57 @@ export default function PermissionSearch() {
           <ul>
             {results.map((req, index) => (
               <li key={req.ID || index} className="border p-2 mt-2">
+                <p>Company: {req.requester_name}</p>
+                <p>Sent: {new Date(req.CreatedAt).toLocaleDateString()}</p>
                 <p>Email: {req.requester_email}</p>
                 <p>Phone: {req.requester_phone}</p>
                 <p>Status: {req.status}</p>
+                <div className="mt-2">
+                  {(req.messages || []).map(m => (
+                    <p key={m.id} className="text-sm">
+                      <span className="text-gray-500">{new Date(m.created_at).toLocaleString()} {m.author_email}:</span> {m.body}
+                    </p>
+                  ))}
+                  <input
+                    type="text"
+                    placeholder="Write a message"
+                    maxLength={2000}
+                    value={replies[req.ID] || ""}
+                    onChange={(e) => setReplies(prev => ({ ...prev, [req.ID]: e.target.value }))}
+                    className="border p-1 rounded mr-2 mt-1"
+                  />
+                  <button
+                    onClick={() => handleReply(req.ID)}
+                    className="bg-blue-500 text-white px-2 py-1 rounded"
+                  >
+                    Send
+                  </button>
+                </div>
+                {req.status === "permitted" && (
+                  <div className="mt-2">
+                    <p>
+                      Shared:{" "}
+                      {req.scopes && req.scopes.length > 0
+                        ? req.scopes
+                            .map(s => s.category ? `category ${s.category}` : s.product_id ? `product #${s.product_id}` : `warehouse #${s.warehouse_id}`)
+                            .join(", ")
+                        : "whole catalog"}
+                    </p>
+                    <input
+                      type="text"
+                      placeholder="Shared categories (comma separated)"
+                      value={categoryInputs[req.ID] ?? (req.scopes || []).filter(s => s.category).map(s => s.category).join(", ")}
+                      onChange={(e) => setCategoryInputs(prev => ({ ...prev, [req.ID]: e.target.value }))}
+                      className="border p-1 rounded mr-2"
+                    />
+                    <button
+                      onClick={() => handleSaveCategories(req)}
+                      className="bg-blue-500 text-white px-2 py-1 rounded"
+                    >
+                      Save
+                    </button>
+                  </div>
+                )}
                 {req.status === "pending" && (
                   <button
                     onClick={() => handlePermit(req.ID)}
@@ -109,6 +277,17 @@ export default function PermissionSearch() {
             ))}
           </ul>
         )}
+        {total > PAGE_SIZE && (
+          <div className="flex items-center gap-2 mt-4">
+            <button disabled={page <= 1} onClick={() => handleSearch(page - 1)} className="border px-2 py-1 rounded disabled:opacity-50">
+              Previous
+            </button>
+            <span>Page {page} of {Math.ceil(total / PAGE_SIZE)}</span>
+            <button disabled={page * PAGE_SIZE >= total} onClick={() => handleSearch(page + 1)} className="border px-2 py-1 rounded disabled:opacity-50">
+              Next
+            </button>
+          </div>
+        )}
       </div>
     </div>
   );
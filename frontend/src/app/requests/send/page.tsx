@@ -4,6 +4,7 @@ import { useState } from "react";
 
 export default function SendRequest() {
   const [sellerEmail, setSellerEmail] = useState("");
+  const [note, setNote] = useState("");
   const [message, setMessage] = useState("");
   const [submitting, setSubmitting] = useState(false);
 
@@ -15,11 +16,12 @@ export default function SendRequest() {
         method: "POST",
         headers: { "Content-Type": "application/json" },
         credentials: "include",
-        body: JSON.stringify({ seller_email: sellerEmail }),
+        body: JSON.stringify({ seller_email: sellerEmail, message: note }),
       });
       if (res.ok) {
         setMessage("Request sent successfully.");
         setSellerEmail("");
+        setNote("");
       } else {
         const errData = await res.json();
         setMessage(errData.error || "Failed to send request.");
@@ -46,6 +48,17 @@ export default function SendRequest() {
             required
           />
         </div>
+        <div>
+          <label className="block mb-1">Message to the seller (optional):</label>
+          <textarea
+            value={note}
+            onChange={(e) => setNote(e.target.value)}
+            maxLength={2000}
+            rows={4}
+            placeholder="Introduce your company and what you would like to buy"
+            className="border p-2 w-full rounded"
+          />
+        </div>
         <button type="submit" disabled={submitting} className="bg-green-500 text-white px-4 py-2 rounded hover:bg-green-600 disabled:opacity-50 disabled:cursor-not-allowed">
           {submitting ? "Sending..." : "Send Request"}
         </button>
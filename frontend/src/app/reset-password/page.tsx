@@ -0,0 +1,94 @@
+"use client";
+
+import { Suspense, useState } from "react";
+import { useRouter, useSearchParams } from "next/navigation";
+
+// ResetPassword sets a new password with the token in the reset link.
+function ResetPassword() {
+  const router = useRouter();
+  const searchParams = useSearchParams();
+  const token = searchParams.get("token") || "";
+  const [password, setPassword] = useState("");
+  const [confirmPassword, setConfirmPassword] = useState("");
+  const [message, setMessage] = useState("");
+  const [submitting, setSubmitting] = useState(false);
+
+  const handleReset = async (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault();
+    setMessage("");
+    if (password !== confirmPassword) {
+      setMessage("The passwords do not match");
+      return;
+    }
+    setSubmitting(true);
+    try {
+      const res = await fetch("/api/password/reset/", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ token, password }),
+      });
+      if (!res.ok) {
+        const errorData = await res.json().catch(() => null);
+        setMessage(errorData?.error || "Failed to reset the password");
+      } else {
+        setMessage("Password reset successfully! Redirecting to login page...");
+        setTimeout(() => {
+          router.push("/login");
+        }, 1500);
+      }
+    } catch (error) {
+      console.error("Reset password error:", error);
+      setMessage("Failed to reset the password");
+    } finally {
+      setSubmitting(false);
+    }
+  };
+
+  return (
+    <div className="flex items-start justify-center bg-white p-6">
+      <div className="w-full max-w-md bg-gray-100 rounded-lg shadow-xl p-8">
+        <h1 className="text-center text-2xl font-bold mb-6">Reset Password</h1>
+        {!token ? (
+          <p className="text-red-600">The reset link is incomplete.</p>
+        ) : (
+          <form onSubmit={handleReset} className="space-y-4">
+            <input
+              type="password"
+              placeholder="New Password (at least 8 characters)"
+              value={password}
+              onChange={(e) => setPassword(e.target.value)}
+              minLength={8}
+              required
+              className="w-full p-3 rounded-md text-black border"
+            />
+            <input
+              type="password"
+              placeholder="Confirm New Password"
+              value={confirmPassword}
+              onChange={(e) => setConfirmPassword(e.target.value)}
+              minLength={8}
+              required
+              className="w-full p-3 rounded-md text-black border"
+            />
+            <button
+              type="submit"
+              disabled={submitting}
+              className="w-full bg-blue-500 text-white p-3 rounded-md hover:bg-blue-600 disabled:opacity-50"
+            >
+              {submitting ? "Saving..." : "Set New Password"}
+            </button>
+          </form>
+        )}
+        {message && <p className={`mt-4 ${message.includes("successfully") ? "text-green-600" : "text-red-600"}`}>{message}</p>}
+      </div>
+    </div>
+  );
+}
+
+export default function ResetPasswordPage() {
+  return (
+    <Suspense>
+      <ResetPassword />
+    </Suspense>
+  );
+}
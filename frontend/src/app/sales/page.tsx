@@ -7,13 +7,13 @@ interface OrderItem {
   id?: number;
   product_id: number;
   quantity: number;
-  price: number;
+  price: string; // decimal string, e.g. "1234.50"
 }
 
 interface Order {
   id?: number;
   company_id: number;
-  total: number;
+  total: string;
   status: string;
   date: string;
   OrderItems?: OrderItem[];
@@ -136,7 +136,7 @@ export default function SalesPage() {
                 <td className="border p-2">
                   {new Date(order.date).toLocaleString()}
                 </td>
-                <td className="border p-2">{order.total.toFixed(2)}</td>
+                <td className="border p-2">{order.total}</td>
                 <td className="border p-2">{order.status}</td>
                 <td className="border p-2">
                   {(order.OrderItems || []).map((item, i) => {
@@ -0,0 +1,148 @@
+'use client';
+
+import { useEffect, useState } from "react";
+
+interface APIKey {
+  ID: number;
+  CreatedAt: string;
+  name: string;
+  prefix: string;
+  scopes: string;
+  expires_at?: string;
+  last_used_at?: string;
+  revoked_at?: string;
+}
+
+const SCOPES = ["catalog", "inventory", "purchasing", "sales", "fulfilment", "finance"];
+
+export default function APIKeys() {
+  const [keys, setKeys] = useState<APIKey[]>([]);
+  const [name, setName] = useState("");
+  const [scopes, setScopes] = useState<string[]>([]);
+  const [newKey, setNewKey] = useState("");
+  const [message, setMessage] = useState("");
+
+  const load = async () => {
+    try {
+      const res = await fetch("/api/settings/api-keys/", { credentials: "include" });
+      if (!res.ok) throw new Error("Failed to fetch API keys");
+      setKeys(await res.json());
+    } catch (error) {
+      console.error(error);
+      setMessage("Failed to load API keys.");
+    }
+  };
+
+  useEffect(() => {
+    load();
+  }, []); // eslint-disable-line react-hooks/exhaustive-deps
+
+  const handleCreate = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setMessage("");
+    try {
+      const res = await fetch("/api/settings/api-keys/", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        credentials: "include",
+        body: JSON.stringify({ name, scopes: scopes.join(",") }),
+      });
+      const data = await res.json().catch(() => null);
+      if (!res.ok) {
+        setMessage(data?.error || "Failed to create API key.");
+        return;
+      }
+      setMessage("API key created successfully.");
+      setNewKey(data.key);
+      setName("");
+      setScopes([]);
+      load();
+    } catch (error) {
+      console.error(error);
+      setMessage("An error occurred.");
+    }
+  };
+
+  const handleRevoke = async (id: number) => {
+    setMessage("");
+    try {
+      const res = await fetch(`/api/settings/api-keys/${id}/`, { method: "DELETE", credentials: "include" });
+      const data = await res.json().catch(() => null);
+      if (!res.ok) {
+        setMessage(data?.error || "Failed to revoke API key.");
+        return;
+      }
+      setMessage("API key revoked successfully.");
+      load();
+    } catch (error) {
+      console.error(error);
+      setMessage("An error occurred.");
+    }
+  };
+
+  const toggleScope = (scope: string) => {
+    setScopes(prev => prev.includes(scope) ? prev.filter(s => s !== scope) : [...prev, scope]);
+  };
+
+  return (
+    <div className="p-4">
+      <h2 className="text-2xl font-bold mb-4">API Keys</h2>
+      {message && <p className={`mb-4 ${message.includes("successfully") ? "text-green-600" : "text-red-600"}`}>{message}</p>}
+      {newKey && (
+        <p className="mb-4 break-all">
+          Copy the key now; it is shown only once: <code>{newKey}</code>
+        </p>
+      )}
+      <form onSubmit={handleCreate} className="mb-6 space-y-2 max-w-md">
+        <input
+          type="text"
+          placeholder="Name, e.g. ERP sync"
+          value={name}
+          onChange={(e) => setName(e.target.value)}
+          required
+          className="border p-2 w-full rounded"
+        />
+        <div className="flex flex-wrap gap-3">
+          {SCOPES.map(scope => (
+            <label key={scope} className="flex items-center gap-1">
+              <input type="checkbox" checked={scopes.includes(scope)} onChange={() => toggleScope(scope)} />
+              {scope}
+            </label>
+          ))}
+        </div>
+        <p className="text-sm text-gray-500">A key may only read and change the areas of its scopes; without a scope it may use every area except company settings.</p>
+        <button type="submit" className="bg-blue-500 text-white px-4 py-2 rounded">Create Key</button>
+      </form>
+      <table className="w-full border">
+        <thead>
+          <tr className="bg-gray-100">
+            <th className="p-2 text-left">Name</th>
+            <th className="p-2 text-left">Key</th>
+            <th className="p-2 text-left">Scopes</th>
+            <th className="p-2 text-left">Last used</th>
+            <th className="p-2"></th>
+          </tr>
+        </thead>
+        <tbody>
+          {keys.map(key => (
+            <tr key={key.ID} className="border-t">
+              <td className="p-2">{key.name}</td>
+              <td className="p-2"><code>{key.prefix}…</code></td>
+              <td className="p-2">{key.scopes || "all"}</td>
+              <td className="p-2">{key.last_used_at ? new Date(key.last_used_at).toLocaleString() : "never"}</td>
+              <td className="p-2">
+                {key.revoked_at ? (
+                  "revoked"
+                ) : (
+                  <button onClick={() => handleRevoke(key.ID)} className="bg-red-500 text-white px-2 py-1 rounded">
+                    Revoke
+                  </button>
+                )}
+              </td>
+            </tr>
+          ))}
+        </tbody>
+      </table>
+    </div>
+  );
+}
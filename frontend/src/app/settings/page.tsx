@@ -2,6 +2,9 @@
 
 import { useEffect, useState } from "react";
 import Tabs, { Tab } from "../components/Tabs";
+import UserManagement from "./users/page";
+import APIKeys from "./api-keys/page";
+import Sessions from "./sessions/page";
 
 interface CompanySettings {
   name: string;
@@ -34,6 +37,8 @@ export default function SettingsPage() {
   const [initialTab, setInitialTab] = useState(0);
   const [profileMessage, setProfileMessage] = useState("");
   const [passwordMessage, setPasswordMessage] = useState("");
+  const [emailVerified, setEmailVerified] = useState(true);
+  const [verifyMessage, setVerifyMessage] = useState("");
 
   // Fetch company settings on mount.
   useEffect(() => {
@@ -49,6 +54,7 @@ export default function SettingsPage() {
             email: data.email,
             currentPassword: "", // leave empty; user enters this when updating profile.
           });
+          setEmailVerified(!!data.email_verified_at);
         } else {
           console.error("Failed to retrieve settings.");
         }
@@ -59,6 +65,19 @@ export default function SettingsPage() {
     fetchSettings();
   }, []);
 
+  // Send the company a new verification link.
+  const handleResendVerification = async () => {
+    setVerifyMessage("");
+    try {
+      const res = await fetch("/api/email/verify/resend/", { method: "POST", credentials: "include" });
+      const data = await res.json().catch(() => null);
+      setVerifyMessage(res.ok ? "Verification email sent successfully." : data?.error || "Failed to send the verification email.");
+    } catch (error) {
+      console.error("Error resending verification email", error);
+      setVerifyMessage("An error occurred while sending the verification email.");
+    }
+  };
+
   // For profile edit inputs.
   const handleEditChange = (e: React.ChangeEvent<HTMLInputElement>) => {
     const { name, value } = e.target;
@@ -83,8 +102,11 @@ export default function SettingsPage() {
         body: JSON.stringify(settings),
       });
       if (res.ok) {
+        const data = await res.json();
         setProfileMessage("Profile updated successfully!");
         setSettings(prev => ({ ...prev, currentPassword: "" }));
+        // A changed email has to be verified again.
+        setEmailVerified(!!data.email_verified_at);
       } else {
         const errData = await res.json().catch(() => null);
         setProfileMessage(errData?.error || "Failed to update profile.");
@@ -137,7 +159,18 @@ export default function SettingsPage() {
             <p><strong>Name:</strong> {settings.name}</p>
             <p><strong>Address:</strong> {settings.address}</p>
             <p><strong>Phone:</strong> {settings.phone}</p>
-            <p><strong>Email:</strong> {settings.email}</p>
+            <p><strong>Email:</strong> {settings.email}{!emailVerified && " (not verified)"}</p>
+            {!emailVerified && (
+              <div className="mt-4">
+                <p className="mb-2">Confirm the email to send permission requests and place orders.</p>
+                <button onClick={handleResendVerification} className="border px-4 py-2 bg-blue-500 text-white">
+                  Resend Verification Email
+                </button>
+                {verifyMessage && (
+                  <p className={`mt-2 ${verifyMessage.includes("successfully") ? "text-green-600" : "text-red-600"}`}>{verifyMessage}</p>
+                )}
+              </div>
+            )}
           </div>
         </div>
       ),
@@ -266,9 +299,21 @@ export default function SettingsPage() {
         </div>
       ),
     },
+    {
+      label: "Users",
+      content: <UserManagement />,
+    },
+    {
+      label: "API Keys",
+      content: <APIKeys />,
+    },
+    {
+      label: "Sessions",
+      content: <Sessions />,
+    },
   ];
 
-  const tabLabels = ["Profile", "Edit Profile", "Change Password"];
+  const tabLabels = ["Profile", "Edit Profile", "Change Password", "Users", "API Keys", "Sessions"];
 
   useEffect(() => {
     const updateTabFromHash = () => {
@@ -0,0 +1,81 @@
+'use client';
+
+import { useEffect, useState } from "react";
+import { signOut } from "next-auth/react";
+
+interface Session {
+  id: number;
+  user_agent: string;
+  ip_address: string;
+  created_at: string;
+  last_used_at: string;
+  expires_at: string;
+}
+
+export default function Sessions() {
+  const [sessions, setSessions] = useState<Session[]>([]);
+  const [message, setMessage] = useState("");
+
+  const load = async () => {
+    try {
+      const res = await fetch("/api/user/sessions/", { credentials: "include" });
+      if (!res.ok) throw new Error("Failed to fetch sessions");
+      setSessions(await res.json());
+    } catch (error) {
+      console.error(error);
+      setMessage("Failed to load sessions.");
+    }
+  };
+
+  useEffect(() => {
+    load();
+  }, []); // eslint-disable-line react-hooks/exhaustive-deps
+
+  // Ends every session, including this one, so the user is logged out here too.
+  const handleLogoutAll = async () => {
+    if (!confirm("Log out of all sessions on every device?")) return;
+    setMessage("");
+    try {
+      const res = await fetch("/api/logout/all/", { method: "POST", credentials: "include" });
+      const data = await res.json().catch(() => null);
+      if (!res.ok) {
+        setMessage(data?.error || "Failed to log out.");
+        return;
+      }
+      signOut({ callbackUrl: "/" });
+    } catch (error) {
+      console.error(error);
+      setMessage("An error occurred.");
+    }
+  };
+
+  return (
+    <div className="p-4">
+      <h2 className="text-2xl font-bold mb-4">Sessions</h2>
+      {message && <p className="mb-4 text-red-600">{message}</p>}
+      <table className="w-full border mb-4">
+        <thead>
+          <tr className="bg-gray-100">
+            <th className="p-2 text-left">Device</th>
+            <th className="p-2 text-left">IP address</th>
+            <th className="p-2 text-left">Logged in</th>
+            <th className="p-2 text-left">Last used</th>
+          </tr>
+        </thead>
+        <tbody>
+          {sessions.map(session => (
+            <tr key={session.id} className="border-t">
+              <td className="p-2">{session.user_agent || "unknown"}</td>
+              <td className="p-2">{session.ip_address}</td>
+              <td className="p-2">{new Date(session.created_at).toLocaleString()}</td>
+              <td className="p-2">{new Date(session.last_used_at).toLocaleString()}</td>
+            </tr>
+          ))}
+        </tbody>
+      </table>
+      <button onClick={handleLogoutAll} className="bg-red-500 text-white px-4 py-2 rounded">
+        Log Out All Sessions
+      </button>
+    </div>
+  );
+}
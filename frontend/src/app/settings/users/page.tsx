@@ -0,0 +1,171 @@
+'use client';
+
+import { useEffect, useState } from "react";
+
+interface CompanyUser {
+  ID: number;
+  email: string;
+  name: string;
+  role: string;
+  status: string;
+}
+
+interface Invitation {
+  ID: number;
+  email: string;
+  role: string;
+  expires_at: string;
+}
+
+const ROLES = ["owner", "admin", "buyer", "warehouse", "accountant", "read_only"];
+
+export default function UserManagement() {
+  const [users, setUsers] = useState<CompanyUser[]>([]);
+  const [invitations, setInvitations] = useState<Invitation[]>([]);
+  const [email, setEmail] = useState("");
+  const [role, setRole] = useState("buyer");
+  const [inviteLink, setInviteLink] = useState("");
+  const [message, setMessage] = useState("");
+
+  const load = async () => {
+    try {
+      const [usersRes, invitationsRes] = await Promise.all([
+        fetch("/api/users/", { credentials: "include" }),
+        fetch("/api/users/invitations/", { credentials: "include" }),
+      ]);
+      if (!usersRes.ok || !invitationsRes.ok) throw new Error("Failed to fetch users");
+      setUsers(await usersRes.json());
+      setInvitations(await invitationsRes.json());
+    } catch (error) {
+      console.error(error);
+      setMessage("Failed to load users.");
+    }
+  };
+
+  useEffect(() => {
+    load();
+  }, []); // eslint-disable-line react-hooks/exhaustive-deps
+
+  // Sends a change and reloads the lists, showing the server's error if any.
+  const send = async (url: string, method: string, body: object | null, success: string) => {
+    setMessage("");
+    try {
+      const res = await fetch(url, {
+        method,
+        headers: { "Content-Type": "application/json" },
+        credentials: "include",
+        body: body ? JSON.stringify(body) : undefined,
+      });
+      const data = await res.json().catch(() => null);
+      if (!res.ok) {
+        setMessage(data?.error || "Request failed.");
+        return null;
+      }
+      setMessage(success);
+      await load();
+      return data;
+    } catch (error) {
+      console.error(error);
+      setMessage("An error occurred.");
+      return null;
+    }
+  };
+
+  const handleInvite = async (e: React.FormEvent) => {
+    e.preventDefault();
+    const data = await send("/api/users/invitations/", "POST", { email, role }, "Invitation created successfully.");
+    if (data) {
+      setEmail("");
+      setInviteLink(`${window.location.origin}/invite/?token=${data.token}`);
+    }
+  };
+
+  return (
+    <div className="p-4">
+      <h2 className="text-2xl font-bold mb-4">Users</h2>
+      {message && <p className={`mb-4 ${message.includes("successfully") ? "text-green-600" : "text-red-600"}`}>{message}</p>}
+      <table className="w-full border mb-6">
+        <thead>
+          <tr className="bg-gray-100">
+            <th className="p-2 text-left">Email</th>
+            <th className="p-2 text-left">Name</th>
+            <th className="p-2 text-left">Role</th>
+            <th className="p-2 text-left">Status</th>
+            <th className="p-2"></th>
+          </tr>
+        </thead>
+        <tbody>
+          {users.map(user => (
+            <tr key={user.ID} className="border-t">
+              <td className="p-2">{user.email}</td>
+              <td className="p-2">{user.name}</td>
+              <td className="p-2">
+                <select
+                  value={user.role}
+                  onChange={(e) => send(`/api/users/${user.ID}/`, "PUT", { role: e.target.value }, "Role updated successfully.")}
+                  className="border p-1 rounded"
+                >
+                  {ROLES.map(r => <option key={r} value={r}>{r}</option>)}
+                </select>
+              </td>
+              <td className="p-2">
+                <button
+                  onClick={() => send(`/api/users/${user.ID}/`, "PUT", { status: user.status === "active" ? "disabled" : "active" }, "Status updated successfully.")}
+                  className="border px-2 py-1 rounded"
+                >
+                  {user.status === "active" ? "Disable" : "Enable"}
+                </button>
+              </td>
+              <td className="p-2">
+                <button
+                  onClick={() => send(`/api/users/${user.ID}/`, "DELETE", null, "User removed successfully.")}
+                  className="bg-red-500 text-white px-2 py-1 rounded"
+                >
+                  Remove
+                </button>
+              </td>
+            </tr>
+          ))}
+        </tbody>
+      </table>
+
+      <h3 className="text-xl font-bold mb-2">Invite a User</h3>
+      <form onSubmit={handleInvite} className="flex gap-2 mb-2">
+        <input
+          type="email"
+          placeholder="Email"
+          value={email}
+          onChange={(e) => setEmail(e.target.value)}
+          required
+          className="border p-2 rounded"
+        />
+        <select value={role} onChange={(e) => setRole(e.target.value)} className="border p-2 rounded">
+          {ROLES.map(r => <option key={r} value={r}>{r}</option>)}
+        </select>
+        <button type="submit" className="bg-blue-500 text-white px-4 py-2 rounded">Invite</button>
+      </form>
+      {inviteLink && (
+        <p className="mb-4 break-all">
+          Send this link to the invited person; it is shown only once: <code>{inviteLink}</code>
+        </p>
+      )}
+      {invitations.length > 0 && (
+        <ul>
+          {invitations.map(inv => (
+            <li key={inv.ID} className="border p-2 mt-2 flex justify-between">
+              <span>
+                {inv.email} ({inv.role}), expires {new Date(inv.expires_at).toLocaleDateString()}
+              </span>
+              <button
+                onClick={() => send(`/api/users/invitations/${inv.ID}/`, "DELETE", null, "Invitation cancelled successfully.")}
+                className="border px-2 py-1 rounded"
+              >
+                Cancel
+              </button>
+            </li>
+          ))}
+        </ul>
+      )}
+    </div>
+  );
+}
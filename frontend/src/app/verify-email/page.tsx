@@ -0,0 +1,53 @@
+"use client";
+
+import Link from "next/link";
+import { Suspense, useEffect, useState } from "react";
+import { useSearchParams } from "next/navigation";
+
+// VerifyEmail confirms the company's email with the token in the verification link.
+function VerifyEmail() {
+  const searchParams = useSearchParams();
+  const token = searchParams.get("token") || "";
+  const [message, setMessage] = useState(token ? "Verifying..." : "The verification link is incomplete.");
+
+  useEffect(() => {
+    if (!token) return;
+    const verify = async () => {
+      try {
+        const res = await fetch("/api/email/verify/", {
+          method: "POST",
+          headers: { "Content-Type": "application/json" },
+          body: JSON.stringify({ token }),
+        });
+        const data = await res.json().catch(() => null);
+        setMessage(res.ok ? "Email verified successfully!" : data?.error || "Failed to verify the email");
+      } catch (error) {
+        console.error("Email verification error:", error);
+        setMessage("Failed to verify the email");
+      }
+    };
+    verify();
+  }, [token]);
+
+  return (
+    <div className="flex items-start justify-center bg-white p-6">
+      <div className="w-full max-w-md bg-gray-100 rounded-lg shadow-xl p-8">
+        <h1 className="text-center text-2xl font-bold mb-6">Verify Email</h1>
+        <p className={message.includes("successfully") ? "text-green-600" : message === "Verifying..." ? "" : "text-red-600"}>{message}</p>
+        <p className="mt-4 text-center">
+          <Link href="/dashboard">
+            <span className="text-blue-400 underline">Go to Dashboard</span>
+          </Link>
+        </p>
+      </div>
+    </div>
+  );
+}
+
+export default function VerifyEmailPage() {
+  return (
+    <Suspense>
+      <VerifyEmail />
+    </Suspense>
+  );
+}